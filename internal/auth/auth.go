@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrMissingToken is returned when a request carries no bearer token
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrInvalidToken is returned when a bearer token fails verification
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+// Authenticator verifies bearer tokens and turns their claims into a Principal
+type Authenticator struct {
+	config *config.AuthConfig
+	logger *logrus.Logger
+	parser *jwt.Parser
+	keys   *keySet
+}
+
+// New creates an authenticator from cfg. Keys from a JWKS file are loaded
+// eagerly, a JWKS URL that cannot be reached yet is retried on first use.
+func New(cfg *config.AuthConfig, logger *logrus.Logger) (*Authenticator, error) {
+	needsSecret, needsKeys := false, false
+	for _, alg := range cfg.Algorithms {
+		if strings.HasPrefix(alg, "HS") {
+			needsSecret = true
+		} else {
+			needsKeys = true
+		}
+	}
+	if needsSecret && cfg.Secret == "" {
+		return nil, errors.New("auth.secret is required for HMAC algorithms")
+	}
+	if needsKeys && cfg.JWKS.File == "" && cfg.JWKS.URL == "" {
+		return nil, errors.New("auth.jwks.file or auth.jwks.url is required for RSA and ECDSA algorithms")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(cfg.Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	a := &Authenticator{
+		config: cfg,
+		logger: logger,
+		parser: jwt.NewParser(opts...),
+	}
+
+	if needsKeys {
+		a.keys = newKeySet(&cfg.JWKS, logger)
+		if err := a.keys.refresh(context.Background()); err != nil {
+			if cfg.JWKS.File != "" {
+				return nil, fmt.Errorf("failed to load JWKS: %w", err)
+			}
+			logger.Warnf("JWKS not loaded yet: %v", err)
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate verifies token and returns the principal it identifies
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		if strings.HasPrefix(t.Method.Alg(), "HS") {
+			return []byte(a.config.Secret.Value()), nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return a.principal(claims), nil
+}
+
+// principal builds the principal from verified claims
+func (a *Authenticator) principal(claims jwt.MapClaims) *Principal {
+	p := &Principal{Claims: claims}
+	p.Subject, _ = claims.GetSubject()
+	p.Issuer, _ = claims.GetIssuer()
+	p.Audience, _ = claims.GetAudience()
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		p.ExpiresAt = exp.Time
+	}
+	p.Roles = stringList(claimValue(claims, a.config.RolesClaim))
+	p.Scopes = stringList(claimValue(claims, a.config.ScopesClaim))
+	return p
+}
+
+// claimValue resolves a dotted claim path such as realm_access.roles
+func claimValue(claims map[string]any, path string) any {
+	if path == "" {
+		return nil
+	}
+	var value any = claims
+	for _, part := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+	return value
+}
+
+// stringList accepts both space separated strings, as used by the OAuth2
+// scope claim, and arrays of strings
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// BearerToken extracts the token of an Authorization header value
+func BearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
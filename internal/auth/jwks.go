@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// minRefreshInterval throttles refresh attempts, so forged key IDs cannot be
+// used to flood the JWKS endpoint and an unavailable endpoint is not called
+// for every request
+const minRefreshInterval = 10 * time.Second
+
+// jwk is a JSON Web Key as defined by RFC 7517, only verification keys are used
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA public key
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC public key
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// keySet caches the public keys of a JWKS document read from a file or URL.
+// Keys are refreshed once older than the refresh interval, and early when a
+// token references an unknown key ID. A single refresh runs at a time,
+// outside the lock, while cached keys keep being served.
+type keySet struct {
+	config *config.JWKSConfig
+	logger *logrus.Logger
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// refreshing is closed when the running refresh ends, nil when none runs
+	refreshing chan struct{}
+}
+
+func newKeySet(cfg *config.JWKSConfig, logger *logrus.Logger) *keySet {
+	return &keySet{
+		config: cfg,
+		logger: logger,
+		client: &http.Client{Timeout: cfg.Timeout},
+		keys:   map[string]crypto.PublicKey{},
+	}
+}
+
+// key returns the public key identified by kid, or the only key when the
+// token carries no key ID
+func (k *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	key, found := k.lookup(kid)
+	stale := k.config.RefreshInterval > 0 && time.Since(k.fetchedAt) > k.config.RefreshInterval
+	if found && !stale {
+		k.mu.Unlock()
+		return key, nil
+	}
+
+	// Attempts are throttled even while the keys are stale, as a failed
+	// refresh leaves them stale
+	done := k.refreshing
+	if done == nil && time.Since(k.attemptedAt) >= minRefreshInterval {
+		done = make(chan struct{})
+		k.refreshing = done
+		k.attemptedAt = time.Now()
+		go k.refreshInBackground(done)
+	}
+	k.mu.Unlock()
+
+	// Serve the cached key while the refresh runs or the JWKS source is unavailable
+	if found {
+		return key, nil
+	}
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		k.mu.Lock()
+		key, found = k.lookup(kid)
+		k.mu.Unlock()
+		if found {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// refreshInBackground refreshes the keys and closes done. It does not use
+// the context of the request starting it, whose callers may give up waiting.
+func (k *keySet) refreshInBackground(done chan struct{}) {
+	defer func() {
+		k.mu.Lock()
+		k.refreshing = nil
+		k.mu.Unlock()
+		close(done)
+	}()
+	if err := k.refresh(context.Background()); err != nil {
+		k.logger.Warnf("Failed to refresh JWKS: %v", err)
+	}
+}
+
+func (k *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// refresh replaces the cached keys with the current JWKS document, the lock
+// is only held to store them
+func (k *keySet) refresh(ctx context.Context) error {
+	data, err := k.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			k.logger.Warnf("Skipping JWKS key %q: %v", key.Kid, err)
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no usable signing keys")
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *keySet) read(ctx context.Context) ([]byte, error) {
+	if k.config.File != "" {
+		return os.ReadFile(k.config.File)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// publicKey decodes the RSA or EC public key of the JWK
+func (j *jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
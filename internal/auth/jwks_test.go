@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, jwk) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, jwk{Kty: "RSA", Kid: kid, Use: "sig", N: encodeBigInt(key.N), E: encodeBigInt(big.NewInt(int64(key.E)))}
+}
+
+func ecJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, jwk) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: encodeBigInt(key.X), Y: encodeBigInt(key.Y)}
+}
+
+// jwksServer serves a JWKS document that tests can replace, counting the
+// requests and holding them while blocked
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     []jwk
+	status   int
+	block    chan struct{}
+	requests atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...jwk) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		keys, status, block := s.keys, s.status, s.block
+		s.mu.Unlock()
+		if block != nil {
+			<-block
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(jwkSet{Keys: keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(status int, keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.keys = status, keys
+}
+
+// hold blocks requests until the returned function is called
+func (s *jwksServer) hold() func() {
+	block := make(chan struct{})
+	s.mu.Lock()
+	s.block = block
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		s.block = nil
+		s.mu.Unlock()
+		close(block)
+	}
+}
+
+func newTestKeySet(t *testing.T, url string, refreshInterval time.Duration) *keySet {
+	t.Helper()
+	k := newKeySet(&config.JWKSConfig{URL: url, RefreshInterval: refreshInterval, Timeout: time.Second}, testLogger())
+	if err := k.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	return k
+}
+
+// waitForRefresh waits until no refresh runs
+func waitForRefresh(t *testing.T, k *keySet) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		k.mu.Lock()
+		refreshing := k.refreshing
+		k.mu.Unlock()
+		if refreshing == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refresh did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestKeySetRefreshesOnUnknownKey(t *testing.T) {
+	_, first := rsaJWK(t, "first")
+	_, second := ecJWK(t, "second")
+	server := newJWKSServer(t, first)
+	k := newTestKeySet(t, server.URL, time.Hour)
+
+	if _, err := k.key(context.Background(), "first"); err != nil {
+		t.Fatalf("key(first) error = %v", err)
+	}
+	// A single key is used for tokens without a key ID
+	if _, err := k.key(context.Background(), ""); err != nil {
+		t.Fatalf("key() without a key ID error = %v", err)
+	}
+	if got := server.requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want cached keys to be served", got)
+	}
+
+	// A rotated key is fetched when a token references it
+	server.set(http.StatusOK, first, second)
+	if _, err := k.key(context.Background(), "second"); err != nil {
+		t.Fatalf("key(second) error = %v", err)
+	}
+	if _, err := k.key(context.Background(), ""); err == nil {
+		t.Errorf("key() without a key ID among several keys error = nil")
+	}
+
+	// Further unknown key IDs are throttled instead of fetching again
+	if _, err := k.key(context.Background(), "forged"); err == nil || !strings.Contains(err.Error(), `unknown signing key "forged"`) {
+		t.Errorf("key(forged) error = %v, want an unknown key", err)
+	}
+	if got := server.requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestKeySetThrottlesFailedRefreshes(t *testing.T) {
+	_, first := rsaJWK(t, "first")
+	server := newJWKSServer(t, first)
+	k := newTestKeySet(t, server.URL, time.Hour)
+	server.set(http.StatusInternalServerError)
+
+	for i := 0; i < 3; i++ {
+		if _, err := k.key(context.Background(), "second"); err == nil {
+			t.Fatalf("key(second) error = nil")
+		}
+	}
+	if got := server.requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want a single attempt within the refresh interval", got)
+	}
+
+	// Once the throttle expires the next unknown key is fetched again
+	k.mu.Lock()
+	k.attemptedAt = time.Now().Add(-minRefreshInterval)
+	k.mu.Unlock()
+	_, second := rsaJWK(t, "second")
+	server.set(http.StatusOK, first, second)
+	if _, err := k.key(context.Background(), "second"); err != nil {
+		t.Errorf("key(second) after the throttle error = %v", err)
+	}
+}
+
+func TestKeySetServesStaleKeysWhileRefreshing(t *testing.T) {
+	_, first := rsaJWK(t, "first")
+	_, second := rsaJWK(t, "second")
+	server := newJWKSServer(t, first)
+	k := newTestKeySet(t, server.URL, time.Minute)
+	k.mu.Lock()
+	k.fetchedAt = time.Now().Add(-time.Hour)
+	k.mu.Unlock()
+
+	server.set(http.StatusOK, second)
+	release := server.hold()
+
+	// The stale key is returned without waiting for the refresh
+	if _, err := k.key(context.Background(), "first"); err != nil {
+		t.Fatalf("key(first) error = %v", err)
+	}
+
+	// Callers of unknown keys wait for the running refresh instead of
+	// starting their own
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = k.key(context.Background(), "second")
+		}()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := k.key(ctx, "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("key(second) with an expiring context error = %v, want the context error", err)
+	}
+
+	release()
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("key(second) error = %v", err)
+		}
+	}
+	waitForRefresh(t, k)
+
+	if got := server.requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want a single refresh", got)
+	}
+	if _, err := k.key(context.Background(), "first"); err == nil {
+		t.Errorf("key(first) error = nil, want the rotated key to be dropped")
+	}
+}
+
+func TestKeySetRefreshErrors(t *testing.T) {
+	_, valid := rsaJWK(t, "valid")
+	_, encryption := rsaJWK(t, "encryption")
+	encryption.Use = "enc"
+	_, offCurve := ecJWK(t, "off-curve")
+	offCurve.Y = offCurve.X
+
+	tests := []struct {
+		name    string
+		status  int
+		keys    []jwk
+		wantErr string
+	}{
+		{name: "status", status: http.StatusServiceUnavailable, keys: []jwk{valid}, wantErr: "unexpected status 503"},
+		{name: "encryption keys", status: http.StatusOK, keys: []jwk{encryption}, wantErr: "no usable signing keys"},
+		{name: "off curve", status: http.StatusOK, keys: []jwk{offCurve}, wantErr: "no usable signing keys"},
+		{name: "key type", status: http.StatusOK, keys: []jwk{{Kty: "oct", Kid: "hmac"}}, wantErr: "no usable signing keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newJWKSServer(t)
+			server.set(tt.status, tt.keys...)
+			k := newKeySet(&config.JWKSConfig{URL: server.URL, Timeout: time.Second}, testLogger())
+			err := k.refresh(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("refresh() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithJWKS(t *testing.T) {
+	rsaKey, rsaPublic := rsaJWK(t, "rsa")
+	ecKey, ecPublic := ecJWK(t, "ec")
+	data, err := json.Marshal(jwkSet{Keys: []jwk{rsaPublic, ecPublic}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(&config.AuthConfig{
+		Algorithms:  []string{"RS256", "ES256"},
+		JWKS:        config.JWKSConfig{File: path, RefreshInterval: time.Hour},
+		Issuer:      "https://issuer.example",
+		RolesClaim:  "realm_access.roles",
+		ScopesClaim: "scope",
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	claims := jwt.MapClaims{
+		"sub":          "alice",
+		"iss":          "https://issuer.example",
+		"exp":          time.Now().Add(time.Minute).Unix(),
+		"scope":        "read write",
+		"realm_access": map[string]any{"roles": []any{"admin"}},
+	}
+
+	for name, token := range map[string]string{
+		"RS256": sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims),
+		"ES256": sign(jwt.SigningMethodES256, "ec", ecKey, claims),
+	} {
+		p, err := a.Authenticate(context.Background(), token)
+		if err != nil {
+			t.Fatalf("Authenticate() with %s error = %v", name, err)
+		}
+		if p.Subject != "alice" || strings.Join(p.Roles, ",") != "admin" || strings.Join(p.Scopes, ",") != "read,write" {
+			t.Errorf("Authenticate() with %s = %+v", name, p)
+		}
+	}
+
+	// A token signed by the RSA key but naming the EC key fails verification
+	if _, err := a.Authenticate(context.Background(), sign(jwt.SigningMethodRS256, "ec", rsaKey, claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate() with a mismatched key error = %v, want ErrInvalidToken", err)
+	}
+	expired := jwt.MapClaims{"sub": "alice", "iss": "https://issuer.example", "exp": time.Now().Add(-time.Minute).Unix()}
+	if _, err := a.Authenticate(context.Background(), sign(jwt.SigningMethodRS256, "rsa", rsaKey, expired)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate() with an expired token error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := a.Authenticate(context.Background(), ""); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("Authenticate() without a token error = %v, want ErrMissingToken", err)
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject identifies the caller, taken from the sub claim
+	Subject  string
+	Issuer   string
+	Audience []string
+	Roles    []string
+	Scopes   []string
+	// ExpiresAt is when the token stops being valid, zero if it never expires
+	ExpiresAt time.Time
+	// Claims holds every claim of the token
+	Claims map[string]any
+}
+
+// HasRole reports whether the principal was granted role
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// HasScope reports whether the token was issued with scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller of ctx, it is not
+// found on public routes and methods or when authentication is disabled
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
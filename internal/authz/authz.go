@@ -0,0 +1,210 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/Gambitier/voidkitgo/pkg/proto/common"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ErrPermissionDenied is returned when a caller is not allowed to access a resource
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Policy decides whether a principal may access a resource, every condition
+// that is set must hold
+type Policy struct {
+	// Roles grants access to principals holding any of the roles
+	Roles []string
+	// Scopes grants access to principals holding all of the scopes
+	Scopes []string
+	// Expr is the source of the expression, kept for audit logs
+	Expr string
+	expr predicate
+}
+
+// NewPolicy compiles a policy
+func NewPolicy(roles, scopes []string, expr string) (*Policy, error) {
+	policy := &Policy{Roles: roles, Scopes: scopes, Expr: expr}
+	if expr != "" {
+		compiled, err := compileExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+		}
+		policy.expr = compiled
+	}
+	return policy, nil
+}
+
+// evaluate returns the reason p is denied, or an empty string if it is allowed
+func (policy *Policy) evaluate(p *auth.Principal) string {
+	if len(policy.Roles) > 0 {
+		allowed := false
+		for _, role := range policy.Roles {
+			if p.HasRole(role) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("requires one of roles %s", strings.Join(policy.Roles, ", "))
+		}
+	}
+	for _, scope := range policy.Scopes {
+		if !p.HasScope(scope) {
+			return fmt.Sprintf("requires scope %s", scope)
+		}
+	}
+	if policy.expr != nil && !policy.expr(p) {
+		return fmt.Sprintf("does not satisfy %s", policy.Expr)
+	}
+	return ""
+}
+
+// Engine holds the policies of gRPC methods and HTTP routes and enforces them
+type Engine struct {
+	logger       *logrus.Logger
+	defaultAllow bool
+	// methods are keyed by full method name, services by service name
+	methods  map[string]*Policy
+	services map[string]*Policy
+	// routes are keyed by "METHOD /path/template"
+	routes map[string]*Policy
+	// configured holds the methods with a policy from config, which take
+	// precedence over proto options
+	configured map[string]bool
+}
+
+// New creates an engine with the rules of cfg
+func New(cfg *config.AuthzConfig, logger *logrus.Logger) (*Engine, error) {
+	e := &Engine{
+		logger:       logger,
+		defaultAllow: cfg.DefaultPolicy == "allow",
+		methods:      map[string]*Policy{},
+		services:     map[string]*Policy{},
+		routes:       map[string]*Policy{},
+		configured:   map[string]bool{},
+	}
+
+	for i, rule := range cfg.Rules {
+		policy, err := NewPolicy(rule.Roles, rule.Scopes, rule.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid authz rule %d: %w", i, err)
+		}
+
+		switch {
+		case rule.Route != "":
+			method, path, ok := strings.Cut(rule.Route, " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid authz rule %d: route must be \"METHOD /path\"", i)
+			}
+			e.routes[routeKey(method, path)] = policy
+		case strings.HasSuffix(rule.Method, "/*"):
+			service := strings.TrimSuffix(strings.TrimPrefix(rule.Method, "/"), "/*")
+			e.services[service] = policy
+		default:
+			e.methods[rule.Method] = policy
+			e.configured[rule.Method] = true
+		}
+	}
+
+	return e, nil
+}
+
+// LoadServiceOptions reads the policy method options of the registered
+// services. Methods whose policy is invalid deny every call.
+func (e *Engine) LoadServiceOptions(serviceNames ...string) error {
+	var errs []error
+	for _, name := range serviceNames {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			// Services without registered descriptors carry no options
+			continue
+		}
+		service, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			fullMethod := fmt.Sprintf("/%s/%s", name, method.Name())
+			if e.configured[fullMethod] {
+				continue
+			}
+			option, ok := proto.GetExtension(method.Options(), common.E_Policy).(*common.Policy)
+			if !ok || option == nil {
+				continue
+			}
+			policy, err := NewPolicy(option.GetRoles(), option.GetScopes(), option.GetExpr())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid policy option of %s: %w", fullMethod, err))
+				policy, _ = NewPolicy(nil, nil, "false")
+			}
+			e.methods[fullMethod] = policy
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AuthorizeMethod checks the principal of ctx against the policy of a gRPC method
+func (e *Engine) AuthorizeMethod(ctx context.Context, fullMethod string) error {
+	policy, ok := e.methods[fullMethod]
+	if !ok {
+		service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+		policy, ok = e.services[service]
+	}
+	return e.authorize(ctx, "grpc "+fullMethod, policy, ok)
+}
+
+// AuthorizeRoute checks the principal of ctx against the policy of an HTTP route
+func (e *Engine) AuthorizeRoute(ctx context.Context, method, route string) error {
+	key := routeKey(method, route)
+	policy, ok := e.routes[key]
+	return e.authorize(ctx, "http "+key, policy, ok)
+}
+
+// authorize evaluates a policy and writes an audit log entry for the decision
+func (e *Engine) authorize(ctx context.Context, resource string, policy *Policy, found bool) error {
+	principal, authenticated := auth.PrincipalFromContext(ctx)
+
+	var reason string
+	switch {
+	case !authenticated:
+		reason = "unauthenticated caller"
+	case !found && !e.defaultAllow:
+		reason = "no policy allows access"
+	case found:
+		reason = policy.evaluate(principal)
+	}
+
+	entry := logging.FromContext(ctx, e.logger).WithFields(logrus.Fields{
+		"audit":    true,
+		"resource": resource,
+		"allowed":  reason == "",
+	})
+	if authenticated {
+		entry = entry.WithField("subject", principal.Subject)
+	}
+
+	if reason != "" {
+		entry.WithField("reason", reason).Warn("Authorization denied")
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, reason)
+	}
+
+	entry.Debug("Authorization granted")
+	return nil
+}
+
+func routeKey(method, route string) string {
+	return strings.ToUpper(method) + " " + route
+}
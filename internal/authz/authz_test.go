@@ -0,0 +1,159 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestEngine(t *testing.T, defaultPolicy string, rules ...config.PolicyRule) *Engine {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	engine, err := New(&config.AuthzConfig{Enabled: true, DefaultPolicy: defaultPolicy, Rules: rules}, logger)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return engine
+}
+
+func withPrincipal(roles, scopes []string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "alice",
+		Roles:   roles,
+		Scopes:  scopes,
+		Claims:  map[string]any{"tenant": "acme"},
+	})
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		roles      []string
+		scopes     []string
+		expr       string
+		principal  *auth.Principal
+		wantReason string
+	}{
+		{name: "empty policy", principal: &auth.Principal{}},
+		{name: "any role", roles: []string{"admin", "ops"}, principal: &auth.Principal{Roles: []string{"ops"}}},
+		{
+			name: "missing role", roles: []string{"admin", "ops"}, principal: &auth.Principal{Roles: []string{"viewer"}},
+			wantReason: "requires one of roles admin, ops",
+		},
+		{name: "all scopes", scopes: []string{"read", "write"}, principal: &auth.Principal{Scopes: []string{"write", "read"}}},
+		{
+			name: "missing scope", scopes: []string{"read", "write"}, principal: &auth.Principal{Scopes: []string{"read"}},
+			wantReason: "requires scope write",
+		},
+		{
+			name: "every condition holds", roles: []string{"admin"}, scopes: []string{"read"}, expr: "subject == 'alice'",
+			principal: &auth.Principal{Subject: "alice", Roles: []string{"admin"}, Scopes: []string{"read"}},
+		},
+		{
+			name: "expression fails", roles: []string{"admin"}, expr: "subject == 'alice'",
+			principal:  &auth.Principal{Subject: "bob", Roles: []string{"admin"}},
+			wantReason: "does not satisfy subject == 'alice'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewPolicy(tt.roles, tt.scopes, tt.expr)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+			if got := policy.evaluate(tt.principal); got != tt.wantReason {
+				t.Errorf("evaluate() = %q, want %q", got, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	for name, rule := range map[string]config.PolicyRule{
+		"expression": {Method: "/svc.v1.Svc/Get", Expr: "has_role(admin)"},
+		"route":      {Route: "/admin"},
+	} {
+		if _, err := New(&config.AuthzConfig{Rules: []config.PolicyRule{rule}}, logger); err == nil {
+			t.Errorf("New() with an invalid %s rule error = nil", name)
+		}
+	}
+}
+
+func TestAuthorizeMethod(t *testing.T) {
+	engine := newTestEngine(t, "deny",
+		config.PolicyRule{Method: "/svc.v1.Svc/*", Roles: []string{"user"}},
+		config.PolicyRule{Method: "/svc.v1.Svc/Delete", Roles: []string{"admin"}},
+		config.PolicyRule{Method: "/svc.v1.Other/Get", Expr: "claims.tenant == 'acme'"},
+	)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		method  string
+		allowed bool
+	}{
+		{name: "service rule", ctx: withPrincipal([]string{"user"}, nil), method: "/svc.v1.Svc/Get", allowed: true},
+		{name: "service rule denies", ctx: withPrincipal([]string{"viewer"}, nil), method: "/svc.v1.Svc/Get"},
+		// A method rule takes precedence over the rule of its service
+		{name: "method rule over service rule", ctx: withPrincipal([]string{"user"}, nil), method: "/svc.v1.Svc/Delete"},
+		{name: "method rule", ctx: withPrincipal([]string{"admin"}, nil), method: "/svc.v1.Svc/Delete", allowed: true},
+		{name: "expression", ctx: withPrincipal(nil, nil), method: "/svc.v1.Other/Get", allowed: true},
+		{name: "default deny", ctx: withPrincipal([]string{"admin"}, nil), method: "/svc.v1.Other/List"},
+		{name: "unauthenticated", ctx: context.Background(), method: "/svc.v1.Svc/Get"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.AuthorizeMethod(tt.ctx, tt.method)
+			if tt.allowed && err != nil {
+				t.Errorf("AuthorizeMethod() error = %v, want allowed", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrPermissionDenied) {
+				t.Errorf("AuthorizeMethod() error = %v, want ErrPermissionDenied", err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRoute(t *testing.T) {
+	engine := newTestEngine(t, "allow",
+		config.PolicyRule{Route: "PUT /admin/log-level", Roles: []string{"admin"}},
+		config.PolicyRule{Route: "get /orders/{id}", Scopes: []string{"orders:read"}},
+	)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		method  string
+		route   string
+		allowed bool
+	}{
+		{name: "role", ctx: withPrincipal([]string{"admin"}, nil), method: "PUT", route: "/admin/log-level", allowed: true},
+		{name: "missing role", ctx: withPrincipal(nil, nil), method: "PUT", route: "/admin/log-level"},
+		// Rules are keyed by method, other methods fall back to the default
+		{name: "other method", ctx: withPrincipal(nil, nil), method: "GET", route: "/admin/log-level", allowed: true},
+		// Methods are matched case-insensitively, templates exactly
+		{name: "template", ctx: withPrincipal(nil, []string{"orders:read"}), method: "get", route: "/orders/{id}", allowed: true},
+		{name: "missing scope", ctx: withPrincipal(nil, nil), method: "GET", route: "/orders/{id}"},
+		{name: "default allow", ctx: withPrincipal(nil, nil), method: "GET", route: "/health", allowed: true},
+		{name: "unauthenticated", ctx: context.Background(), method: "GET", route: "/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.AuthorizeRoute(tt.ctx, tt.method, tt.route)
+			if tt.allowed && err != nil {
+				t.Errorf("AuthorizeRoute() error = %v, want allowed", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrPermissionDenied) {
+				t.Errorf("AuthorizeRoute() error = %v, want ErrPermissionDenied", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+)
+
+// predicate is a compiled policy expression
+type predicate func(p *auth.Principal) bool
+
+// compileExpr parses a policy expression. The grammar is:
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | primary
+//	primary    = "(" expr ")" | "true" | "false" | call | comparison
+//	call       = ( "has_role" | "has_scope" ) "(" string ")"
+//	comparison = field ( "==" | "!=" ) string
+//	field      = "subject" | "issuer" | "claims." name { "." name }
+//
+// Strings are quoted with single or double quotes.
+func compileExpr(src string) (predicate, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return pred, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators are matched longest first
+var operators = []string{"||", "&&", "==", "!=", "!", "(", ")"}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '\'' || c == '"':
+			end := strings.IndexRune(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: src[i+1 : i+1+end], pos: i})
+			i += end + 2
+
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '.' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if tok := p.peek(); tok.kind == kind && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if !p.accept(kind, text) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d", text, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) expectString() (string, error) {
+	tok := p.next()
+	if tok.kind != tokenString {
+		return "", fmt.Errorf("expected string at position %d", tok.pos)
+	}
+	return tok.text, nil
+}
+
+func (p *parser) parseOr() (predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOperator, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(pr *auth.Principal) bool { return l(pr) || right(pr) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOperator, "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(pr *auth.Principal) bool { return l(pr) && right(pr) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (predicate, error) {
+	if p.accept(tokenOperator, "!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(pr *auth.Principal) bool { return !operand(pr) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (predicate, error) {
+	if p.accept(tokenOperator, "(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenOperator, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	switch tok.text {
+	case "true":
+		return func(*auth.Principal) bool { return true }, nil
+	case "false":
+		return func(*auth.Principal) bool { return false }, nil
+	case "has_role", "has_scope":
+		if err := p.expect(tokenOperator, "("); err != nil {
+			return nil, err
+		}
+		arg, err := p.expectString()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenOperator, ")"); err != nil {
+			return nil, err
+		}
+		if tok.text == "has_role" {
+			return func(pr *auth.Principal) bool { return pr.HasRole(arg) }, nil
+		}
+		return func(pr *auth.Principal) bool { return pr.HasScope(arg) }, nil
+	}
+
+	field, err := fieldAccessor(tok)
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	if op.kind != tokenOperator || (op.text != "==" && op.text != "!=") {
+		return nil, fmt.Errorf("expected comparison after %q at position %d", tok.text, op.pos)
+	}
+	value, err := p.expectString()
+	if err != nil {
+		return nil, err
+	}
+	if op.text == "==" {
+		return func(pr *auth.Principal) bool { return field(pr) == value }, nil
+	}
+	return func(pr *auth.Principal) bool { return field(pr) != value }, nil
+}
+
+// fieldAccessor returns a function reading a principal field as a string
+func fieldAccessor(tok token) (func(p *auth.Principal) string, error) {
+	switch tok.text {
+	case "subject":
+		return func(p *auth.Principal) string { return p.Subject }, nil
+	case "issuer":
+		return func(p *auth.Principal) string { return p.Issuer }, nil
+	}
+
+	path, ok := strings.CutPrefix(tok.text, "claims.")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("unknown identifier %q at position %d", tok.text, tok.pos)
+	}
+	parts := strings.Split(path, ".")
+	return func(p *auth.Principal) string {
+		var value any = p.Claims
+		for _, part := range parts {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return ""
+			}
+			value = object[part]
+		}
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}, nil
+}
@@ -0,0 +1,91 @@
+package authz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+)
+
+func TestCompileExpr(t *testing.T) {
+	principal := &auth.Principal{
+		Subject: "alice",
+		Issuer:  "https://issuer.example",
+		Roles:   []string{"admin", "ops"},
+		Scopes:  []string{"read"},
+		Claims: map[string]any{
+			"tenant": "acme",
+			"org":    map[string]any{"id": "42", "tier": "gold"},
+			"level":  3,
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "true", want: true},
+		{expr: "false", want: false},
+		{expr: "has_role('admin')", want: true},
+		{expr: `has_role("viewer")`, want: false},
+		{expr: "has_scope('read')", want: true},
+		{expr: "has_scope('write')", want: false},
+		{expr: "subject == 'alice'", want: true},
+		{expr: "subject != 'alice'", want: false},
+		{expr: "issuer == 'https://issuer.example'", want: true},
+		{expr: "claims.tenant == 'acme'", want: true},
+		{expr: "claims.org.id == '42'", want: true},
+		{expr: "claims.level == '3'", want: true},
+		// Missing claims and paths through non-objects compare as empty
+		{expr: "claims.missing == ''", want: true},
+		{expr: "claims.tenant.name == ''", want: true},
+		{expr: "!has_role('admin')", want: false},
+		{expr: "!!has_role('admin')", want: true},
+		// && binds tighter than ||
+		{expr: "true || false && false", want: true},
+		{expr: "(true || false) && false", want: false},
+		{expr: "has_role('viewer') || claims.tenant == 'acme' && has_scope('read')", want: true},
+		{expr: "!(has_role('admin') && has_scope('write'))", want: true},
+		{expr: "  has_role( 'ops' )&&subject=='alice'  ", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			pred, err := compileExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("compileExpr() error = %v", err)
+			}
+			if got := pred(principal); got != tt.want {
+				t.Errorf("evaluated to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "", wantErr: `unexpected "" at position 0`},
+		{expr: "has_role('admin'", wantErr: `expected ")" at position 16`},
+		{expr: "has_role(admin)", wantErr: "expected string at position 9"},
+		{expr: "has_role 'admin'", wantErr: `expected "(" at position 9`},
+		{expr: "subject", wantErr: `expected comparison after "subject"`},
+		{expr: "subject == alice", wantErr: "expected string at position 11"},
+		{expr: "roles == 'x'", wantErr: `unknown identifier "roles" at position 0`},
+		{expr: "claims. == 'x'", wantErr: `unknown identifier "claims."`},
+		{expr: "true true", wantErr: `unexpected "true" at position 5`},
+		{expr: "(true", wantErr: `expected ")" at position 5`},
+		{expr: "subject == 'alice", wantErr: "unterminated string at position 11"},
+		{expr: "true & false", wantErr: "unexpected character '&' at position 5"},
+		{expr: "true ||", wantErr: `unexpected "" at position 7`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := compileExpr(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("compileExpr() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound is returned when a key is not in the cache
+var ErrNotFound = errors.New("cache: key not found")
+
+// Cache stores byte values with an expiration, shared by every service
+type Cache interface {
+	// Get returns the value of key or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key, a zero ttl uses the default TTL
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys, missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+	// TTL returns the remaining time to live of key or ErrNotFound, it is
+	// zero for keys without expiration
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// GetOrLoad returns the value of key, calling load and storing its result
+	// on a miss. Concurrent misses of the same key share a single load.
+	GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error)
+	// Ping checks the backend is reachable
+	Ping(ctx context.Context) error
+}
+
+// Observer receives the outcome of every cache operation, such as the metrics
+type Observer interface {
+	// ObserveCache records an operation: get, set, delete, ttl or load, with a
+	// result of hit, miss, ok or error
+	ObserveCache(operation, result string, duration time.Duration)
+}
+
+// backend is the storage of a cache, keys are already prefixed
+type backend interface {
+	get(ctx context.Context, key string) ([]byte, error)
+	set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	delete(ctx context.Context, keys ...string) error
+	ttl(ctx context.Context, key string) (time.Duration, error)
+	ping(ctx context.Context) error
+}
+
+// Options configures a cache
+type Options struct {
+	// KeyPrefix namespaces every key
+	KeyPrefix string
+	// DefaultTTL applies when Set is called with a zero ttl, zero never expires
+	DefaultTTL time.Duration
+	// MaxEntries bounds the entries of the in-memory cache, zero is unbounded.
+	// Redis is bounded by its own maxmemory policy.
+	MaxEntries int
+	// Observer is notified of every operation, nil disables it
+	Observer Observer
+}
+
+// cache implements Cache on top of a backend, adding key prefixes, default
+// TTLs, load deduplication and observation
+type cache struct {
+	backend backend
+	options Options
+	loads   singleflight.Group
+}
+
+func newCache(b backend, options Options) *cache {
+	return &cache{backend: b, options: options}
+}
+
+func (c *cache) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := c.backend.get(ctx, c.options.KeyPrefix+key)
+	c.observe("get", start, err)
+	return value, err
+}
+
+func (c *cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = c.options.DefaultTTL
+	}
+	start := time.Now()
+	err := c.backend.set(ctx, c.options.KeyPrefix+key, value, ttl)
+	c.observe("set", start, err)
+	return err
+}
+
+func (c *cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.options.KeyPrefix + key
+	}
+	start := time.Now()
+	err := c.backend.delete(ctx, prefixed...)
+	c.observe("delete", start, err)
+	return err
+}
+
+func (c *cache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	start := time.Now()
+	ttl, err := c.backend.ttl(ctx, c.options.KeyPrefix+key)
+	c.observe("ttl", start, err)
+	return ttl, err
+}
+
+func (c *cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	// Backend failures are treated as misses so callers still get a value,
+	// unless the caller has gone away
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result, err, shared := c.loads.Do(key, func() (any, error) {
+		// The load is shared by every waiting caller, so neither it nor storing
+		// its result is cancelled when the first caller goes away
+		loadCtx := context.WithoutCancel(ctx)
+		start := time.Now()
+		loaded, err := load(loadCtx)
+		c.observe("load", start, err)
+		if err != nil {
+			return nil, err
+		}
+		// Failing to store the value does not fail the load
+		c.Set(loadCtx, key, loaded, ttl)
+		return loaded, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers sharing a load each get their own copy they may modify
+	if shared {
+		return bytes.Clone(result.([]byte)), nil
+	}
+	return result.([]byte), nil
+}
+
+func (c *cache) Ping(ctx context.Context) error {
+	return c.backend.ping(ctx)
+}
+
+func (c *cache) observe(operation string, start time.Time, err error) {
+	if c.options.Observer == nil {
+		return
+	}
+	result := "ok"
+	switch {
+	case errors.Is(err, ErrNotFound):
+		result = "miss"
+	case err != nil:
+		result = "error"
+	case operation == "get":
+		result = "hit"
+	}
+	c.options.Observer.ObserveCache(operation, result, time.Since(start))
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired entries are evicted from memory
+const sweepInterval = time.Minute
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	// element is the position of the entry in the recency list
+	element *list.Element
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// memoryBackend keeps entries in process, each instance has its own cache
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	// recent orders the entries from the most to the least recently used
+	recent     *list.List
+	maxEntries int
+	lastSweep  time.Time
+}
+
+// NewMemory creates an in-memory cache, suited to tests and single instances.
+// It holds at most options.MaxEntries entries, evicting the least recently
+// used ones.
+func NewMemory(options Options) Cache {
+	return newCache(&memoryBackend{
+		entries:    map[string]*entry{},
+		recent:     list.New(),
+		maxEntries: options.MaxEntries,
+		lastSweep:  time.Now(),
+	}, options)
+}
+
+func (m *memoryBackend) lookup(key string) (*entry, bool) {
+	now := time.Now()
+	m.sweep(now)
+
+	e, ok := m.entries[key]
+	if !ok || e.expired(now) {
+		return nil, false
+	}
+	m.recent.MoveToFront(e.element)
+	return e, true
+}
+
+func (m *memoryBackend) get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.lookup(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	// Callers may modify the returned slice
+	return append([]byte(nil), e.value...), nil
+}
+
+func (m *memoryBackend) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	e := &entry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	if old, ok := m.entries[key]; ok {
+		m.remove(old)
+	}
+	m.evict(now)
+	e.element = m.recent.PushFront(e)
+	m.entries[key] = e
+	return nil
+}
+
+func (m *memoryBackend) delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if e, ok := m.entries[key]; ok {
+			m.remove(e)
+		}
+	}
+	return nil
+}
+
+func (m *memoryBackend) ttl(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.lookup(key)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if e.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(e.expiresAt), nil
+}
+
+func (m *memoryBackend) ping(ctx context.Context) error {
+	return nil
+}
+
+// sweep evicts expired entries at most once per sweep interval
+func (m *memoryBackend) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	m.removeExpired(now)
+}
+
+// evict makes room for a new entry when the cache is full, removing the
+// expired entries then the least recently used ones
+func (m *memoryBackend) evict(now time.Time) {
+	if m.maxEntries <= 0 || len(m.entries) < m.maxEntries {
+		return
+	}
+	m.removeExpired(now)
+	for len(m.entries) >= m.maxEntries {
+		m.remove(m.recent.Back().Value.(*entry))
+	}
+}
+
+func (m *memoryBackend) removeExpired(now time.Time) {
+	for _, e := range m.entries {
+		if e.expired(now) {
+			m.remove(e)
+		}
+	}
+}
+
+func (m *memoryBackend) remove(e *entry) {
+	m.recent.Remove(e.element)
+	delete(m.entries, e.key)
+}
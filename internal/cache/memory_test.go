@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryGetSet(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory(Options{KeyPrefix: "test:", DefaultTTL: time.Hour})
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a missing key error = %v, want ErrNotFound", err)
+	}
+
+	value := []byte("one")
+	if err := c.Set(ctx, "a", value, 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	// The cache keeps its own copies of values
+	value[0] = 'x'
+	got, err := c.Get(ctx, "a")
+	if err != nil || string(got) != "one" {
+		t.Fatalf("Get() = %q, %v, want one", got, err)
+	}
+	got[0] = 'x'
+	if got, _ := c.Get(ctx, "a"); string(got) != "one" {
+		t.Errorf("Get() after modifying the result = %q, want one", got)
+	}
+
+	// Zero ttls use the default TTL
+	if ttl, err := c.TTL(ctx, "a"); err != nil || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TTL() = %s, %v, want about an hour", ttl, err)
+	}
+
+	if err := c.Delete(ctx, "a", "missing"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory(Options{})
+
+	if err := c.Set(ctx, "short", []byte("v"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "forever", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := c.TTL(ctx, "forever"); err != nil || ttl != 0 {
+		t.Errorf("TTL() without expiration = %s, %v, want 0", ttl, err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of an expired key error = %v, want ErrNotFound", err)
+	}
+	if _, err := c.TTL(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TTL() of an expired key error = %v, want ErrNotFound", err)
+	}
+	if _, err := c.Get(ctx, "forever"); err != nil {
+		t.Errorf("Get() of a key without expiration error = %v", err)
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory(Options{}).(*cache)
+	m := c.backend.(*memoryBackend)
+
+	c.Set(ctx, "expired", []byte("v"), time.Millisecond)
+	c.Set(ctx, "live", []byte("v"), time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	// Expired entries stay in memory until the next sweep
+	c.Get(ctx, "live")
+	if len(m.entries) != 2 {
+		t.Fatalf("entries = %d before the sweep interval, want 2", len(m.entries))
+	}
+
+	m.lastSweep = time.Now().Add(-sweepInterval)
+	c.Get(ctx, "live")
+	if _, ok := m.entries["expired"]; ok || len(m.entries) != 1 || m.recent.Len() != 1 {
+		t.Errorf("entries = %d, recent = %d after the sweep, want the live entry only", len(m.entries), m.recent.Len())
+	}
+}
+
+func TestMemoryMaxEntries(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory(Options{MaxEntries: 3}).(*cache)
+	m := c.backend.(*memoryBackend)
+
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(ctx, key, []byte(key), time.Hour)
+	}
+	// Reading a makes b the least recently used entry
+	c.Get(ctx, "a")
+	c.Set(ctx, "d", []byte("d"), time.Hour)
+	assertKeys(t, c, "a", "c", "d")
+
+	// Replacing an entry does not evict another one
+	c.Set(ctx, "c", []byte("c2"), time.Hour)
+	assertKeys(t, c, "a", "c", "d")
+
+	// Expired entries are evicted first
+	c.Set(ctx, "a", []byte("a"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	c.Set(ctx, "e", []byte("e"), time.Hour)
+	assertKeys(t, c, "c", "d", "e")
+
+	if len(m.entries) != 3 || m.recent.Len() != 3 {
+		t.Errorf("entries = %d, recent = %d, want 3", len(m.entries), m.recent.Len())
+	}
+}
+
+// assertKeys checks the cache holds exactly keys among a to e
+func assertKeys(t *testing.T, c Cache, keys ...string) {
+	t.Helper()
+	want := map[string]bool{}
+	for _, key := range keys {
+		want[key] = true
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		_, err := c.Get(context.Background(), key)
+		if got := err == nil; got != want[key] {
+			t.Errorf("%s cached = %v, want %v", key, got, want[key])
+		}
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory(Options{})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("loaded"), nil
+	}
+
+	const callers = 10
+	results := make([][]byte, callers)
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for i := range callers {
+		go func() {
+			defer done.Done()
+			started.Done()
+			value, err := c.GetOrLoad(ctx, "key", time.Hour, load)
+			if err != nil {
+				t.Errorf("GetOrLoad() error = %v", err)
+			}
+			results[i] = value
+		}()
+	}
+	started.Wait()
+	// Let the callers reach the shared load before it completes
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("load called %d times, want once for concurrent misses", got)
+	}
+	for i, value := range results {
+		if string(value) != "loaded" {
+			t.Fatalf("GetOrLoad() = %q, want loaded", value)
+		}
+		// Callers sharing a load get their own copies
+		for j := range i {
+			if &value[0] == &results[j][0] {
+				t.Fatalf("callers %d and %d share the same slice", j, i)
+			}
+		}
+	}
+
+	// Later calls hit the stored value
+	value, err := c.GetOrLoad(ctx, "key", time.Hour, func(ctx context.Context) ([]byte, error) {
+		t.Error("load called on a hit")
+		return nil, nil
+	})
+	if err != nil || string(value) != "loaded" {
+		t.Errorf("GetOrLoad() on a hit = %q, %v, want loaded", value, err)
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory(Options{})
+	errLoad := errors.New("load failed")
+
+	if _, err := c.GetOrLoad(ctx, "key", time.Hour, func(ctx context.Context) ([]byte, error) {
+		return nil, errLoad
+	}); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad() error = %v, want the load error", err)
+	}
+	// Failed loads are not cached
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after a failed load error = %v, want ErrNotFound", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.GetOrLoad(cancelled, "key", time.Hour, func(ctx context.Context) ([]byte, error) {
+		t.Error("load called for a cancelled caller")
+		return nil, nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetOrLoad() of a cancelled caller error = %v, want context.Canceled", err)
+	}
+}
+
+type observation struct{ operation, result string }
+
+type recordingObserver struct {
+	mu           sync.Mutex
+	observations []observation
+}
+
+func (o *recordingObserver) ObserveCache(operation, result string, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.observations = append(o.observations, observation{operation, result})
+}
+
+func TestObserver(t *testing.T) {
+	ctx := context.Background()
+	observer := &recordingObserver{}
+	c := NewMemory(Options{Observer: observer})
+
+	c.GetOrLoad(ctx, "key", 0, func(ctx context.Context) ([]byte, error) { return []byte("v"), nil })
+	c.Get(ctx, "key")
+	c.Delete(ctx, "key")
+
+	want := []observation{
+		{"get", "miss"},
+		{"load", "ok"},
+		{"set", "ok"},
+		{"get", "hit"},
+		{"delete", "ok"},
+	}
+	if len(observer.observations) != len(want) {
+		t.Fatalf("observations = %v, want %v", observer.observations, want)
+	}
+	for i := range want {
+		if observer.observations[i] != want[i] {
+			t.Errorf("observations = %v, want %v", observer.observations, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisBackend keeps entries in Redis, shared by every instance
+type redisBackend struct {
+	client redis.UniversalClient
+}
+
+// NewRedis creates a cache backed by client
+func NewRedis(client redis.UniversalClient, options Options) Cache {
+	return newCache(&redisBackend{client: client}, options)
+}
+
+func (r *redisBackend) get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (r *redisBackend) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *redisBackend) delete(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *redisBackend) ttl(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// The client passes through -2 for missing keys and -1 for keys without
+	// expiration without scaling them
+	switch ttl {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *redisBackend) ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// NewRedisClient creates the Redis client shared by the cache and every other
+// Redis user, connections are established lazily
+func NewRedisClient(cfg *config.CacheConfig) redis.UniversalClient {
+	return redis.NewClient(&redis.Options{
+		Addr:        net.JoinHostPort(cfg.Host, cfg.Port),
+		Password:    cfg.Password.Value(),
+		DB:          cfg.DB,
+		PoolSize:    cfg.PoolSize,
+		DialTimeout: cfg.DialTimeout,
+	})
+}
+
+// RedisClient is a server component closing the shared Redis client on
+// shutdown. It does not wait for Redis on start, availability is reported by
+// the cache readiness check instead.
+type RedisClient struct {
+	client redis.UniversalClient
+	stop   chan struct{}
+	ready  atomic.Bool
+}
+
+// NewRedisClientComponent wraps client so the server closes it on shutdown
+func NewRedisClientComponent(client redis.UniversalClient) *RedisClient {
+	return &RedisClient{client: client, stop: make(chan struct{})}
+}
+
+// Name returns the component name
+func (r *RedisClient) Name() string {
+	return "redis client"
+}
+
+// Start blocks until the component is stopped
+func (r *RedisClient) Start(ctx context.Context) error {
+	r.ready.Store(true)
+	<-r.stop
+	return nil
+}
+
+// Stop closes the client connections
+func (r *RedisClient) Stop(ctx context.Context) error {
+	r.ready.Store(false)
+	close(r.stop)
+	return r.client.Close()
+}
+
+// Ready reports whether the component has started
+func (r *RedisClient) Ready() bool {
+	return r.ready.Load()
+}
@@ -0,0 +1,62 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// Peer is the identity a client proved with its certificate during an mTLS handshake
+type Peer struct {
+	// Subject is the distinguished name of the client certificate
+	Subject    string
+	CommonName string
+	DNSNames   []string
+	// URIs hold URI SANs, such as SPIFFE IDs
+	URIs           []string
+	EmailAddresses []string
+	SerialNumber   string
+	// Verified reports whether the certificate chained to a trusted CA
+	Verified bool
+	// Certificate is the leaf certificate presented by the client
+	Certificate *x509.Certificate
+}
+
+type peerKey struct{}
+
+// WithPeer returns a copy of ctx carrying the peer identity
+func WithPeer(ctx context.Context, peer *Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, peer)
+}
+
+// PeerFromContext returns the mTLS peer identity of ctx, if the client
+// presented a certificate
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+	peer, ok := ctx.Value(peerKey{}).(*Peer)
+	return peer, ok
+}
+
+// PeerFromConnectionState extracts the peer identity of a handshake, it
+// returns nil when the client presented no certificate
+func PeerFromConnectionState(state *tls.ConnectionState) *Peer {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	cert := state.PeerCertificates[0]
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	return &Peer{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		URIs:           uris,
+		EmailAddresses: cert.EmailAddresses,
+		SerialNumber:   cert.SerialNumber.String(),
+		Verified:       len(state.VerifiedChains) > 0,
+		Certificate:    cert,
+	}
+}
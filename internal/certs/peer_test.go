@@ -0,0 +1,61 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+)
+
+func TestPeerFromConnectionState(t *testing.T) {
+	if peer := PeerFromConnectionState(nil); peer != nil {
+		t.Errorf("PeerFromConnectionState(nil) = %v, want nil", peer)
+	}
+	if peer := PeerFromConnectionState(&tls.ConnectionState{}); peer != nil {
+		t.Errorf("PeerFromConnectionState() without a certificate = %v, want nil", peer)
+	}
+
+	cert := newCertificate(t, "client")
+	tests := []struct {
+		name         string
+		state        *tls.ConnectionState
+		wantVerified bool
+	}{
+		{
+			name:  "unverified",
+			state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		},
+		{
+			name: "verified",
+			state: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+				VerifiedChains:   [][]*x509.Certificate{{cert}},
+			},
+			wantVerified: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := PeerFromConnectionState(tt.state)
+			if peer == nil {
+				t.Fatal("PeerFromConnectionState() = nil")
+			}
+			if peer.CommonName != "client" || peer.Subject != "CN=client" || len(peer.DNSNames) != 1 || peer.Certificate != cert {
+				t.Errorf("peer = %+v, want the client certificate", peer)
+			}
+			if peer.SerialNumber != cert.SerialNumber.String() || peer.Verified != tt.wantVerified {
+				t.Errorf("SerialNumber = %s, Verified = %v, want %s, %v", peer.SerialNumber, peer.Verified, cert.SerialNumber, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestPeerContext(t *testing.T) {
+	if _, ok := PeerFromContext(context.Background()); ok {
+		t.Error("PeerFromContext() without a peer ok = true")
+	}
+	peer := &Peer{CommonName: "client"}
+	if got, ok := PeerFromContext(WithPeer(context.Background(), peer)); !ok || got != peer {
+		t.Errorf("PeerFromContext() = %v, %v, want the peer", got, ok)
+	}
+}
@@ -0,0 +1,226 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// reloadDebounce coalesces the burst of events produced by a single file update
+const reloadDebounce = 100 * time.Millisecond
+
+// Reloader keeps the certificate, key and client CAs of a listener loaded from
+// disk and reloads them when the files change. Reloading only affects new
+// handshakes, established connections are never dropped.
+type Reloader struct {
+	name   string
+	cfg    *config.TLSConfig
+	logger *logrus.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+
+	watcher *fsnotify.Watcher
+	ready   atomic.Bool
+}
+
+// NewReloader loads the files of cfg, failing if any of them is invalid
+func NewReloader(name string, cfg *config.TLSConfig, logger *logrus.Logger) (*Reloader, error) {
+	r := &Reloader{
+		name:   name,
+		cfg:    cfg,
+		logger: logger,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ServerTLSConfig returns a server configuration always presenting the latest
+// certificate and verifying clients against the latest CAs
+func (r *Reloader) ServerTLSConfig(nextProtos ...string) (*tls.Config, error) {
+	minVersion, err := ParseVersion(r.cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := ParseClientAuth(r.cfg.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		ClientAuth:     clientAuth,
+		NextProtos:     nextProtos,
+		GetCertificate: r.getCertificate,
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientCAs = r.getClientCAs()
+		return cfg, nil
+	}
+
+	return base, nil
+}
+
+func (r *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *Reloader) getClientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clientCAs
+}
+
+// reload loads every file, keeping the previous material if any fails
+func (r *Reloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load %s certificate: %w", r.name, err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.CAFile != "" {
+		clientCAs, err = LoadCertPool(r.cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to load %s client CAs: %w", r.name, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Name returns the component name
+func (r *Reloader) Name() string {
+	return r.name + " certificate reloader"
+}
+
+// Start watches the certificate files and blocks until the reloader is stopped.
+// Directories are watched rather than files so atomic replacements, such as
+// Kubernetes secret updates, are detected.
+func (r *Reloader) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	r.watcher = watcher
+
+	watched := map[string]bool{}
+	for _, file := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(file)
+		if watched[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+		watched[dir] = true
+	}
+
+	r.ready.Store(true)
+	defer r.ready.Store(false)
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			debounce = time.After(reloadDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.logger.Errorf("Certificate watcher error for %s: %v", r.name, err)
+
+		case <-debounce:
+			if err := r.reload(); err != nil {
+				r.logger.Errorf("Keeping previous %s certificate: %v", r.name, err)
+				continue
+			}
+			r.logger.Infof("Reloaded %s certificate", r.name)
+		}
+	}
+}
+
+// Stop stops watching the certificate files
+func (r *Reloader) Stop(ctx context.Context) error {
+	if r.watcher == nil {
+		return nil
+	}
+	return r.watcher.Close()
+}
+
+// Ready reports whether the certificate files are being watched
+func (r *Reloader) Ready() bool {
+	return r.ready.Load()
+}
+
+// LoadCertPool reads the PEM encoded certificates of file into a pool
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no valid certificates found")
+	}
+	return pool, nil
+}
+
+// ParseVersion converts a configured minimum version such as "1.3"
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q", version)
+}
+
+// ParseClientAuth converts a configured client authentication mode
+func ParseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require_and_verify":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("unsupported client auth mode %q", mode)
+}
@@ -0,0 +1,235 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newCertificate returns a self-signed certificate for commonName
+func newCertificate(t *testing.T, commonName string) *x509.Certificate {
+	t.Helper()
+	cert, _ := newCertificatePEM(t, commonName)
+	block, _ := pem.Decode(cert)
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// newCertificatePEM returns a PEM encoded self-signed certificate for
+// commonName and its key
+func newCertificatePEM(t *testing.T, commonName string) (cert, key []byte) {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeCertificate writes a certificate for commonName and its key in dir,
+// replacing the files atomically like a Kubernetes secret update
+func writeCertificate(t *testing.T, dir, commonName string) *config.TLSConfig {
+	t.Helper()
+	cert, key := newCertificatePEM(t, commonName)
+	cfg := &config.TLSConfig{
+		CertFile: filepath.Join(dir, "tls.crt"),
+		KeyFile:  filepath.Join(dir, "tls.key"),
+	}
+	for path, data := range map[string][]byte{cfg.KeyFile: key, cfg.CertFile: cert} {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+// servedName returns the common name of the certificate r presents
+func servedName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.getCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloaderSwapsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewReloader("test", writeCertificate(t, dir, "first"), testLogger())
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- r.Start(context.Background()) }()
+	defer func() {
+		r.Stop(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	}()
+	for deadline := time.Now().Add(5 * time.Second); !r.Ready(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("reloader not ready")
+		}
+	}
+	if got := servedName(t, r); got != "first" {
+		t.Fatalf("served certificate = %s, want first", got)
+	}
+
+	writeCertificate(t, dir, "second")
+	for deadline := time.Now().Add(5 * time.Second); servedName(t, r) != "second"; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate not reloaded after the files changed")
+		}
+	}
+}
+
+func TestReloaderKeepsCertificateOnError(t *testing.T) {
+	dir := t.TempDir()
+	cfg := writeCertificate(t, dir, "first")
+	r, err := NewReloader("test", cfg, testLogger())
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+
+	if err := os.WriteFile(cfg.CertFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err == nil {
+		t.Fatal("reload() of an invalid certificate error = nil")
+	}
+	if got := servedName(t, r); got != "first" {
+		t.Errorf("served certificate = %s, want the previous one", got)
+	}
+
+	if _, err := NewReloader("test", cfg, testLogger()); err == nil {
+		t.Error("NewReloader() of an invalid certificate error = nil")
+	}
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg := writeCertificate(t, dir, "server")
+	cfg.CAFile = cfg.CertFile
+	cfg.MinVersion = "1.3"
+	cfg.ClientAuth = "require_and_verify"
+	r, err := NewReloader("test", cfg, testLogger())
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+
+	tlsConfig, err := r.ServerTLSConfig("h2")
+	if err != nil {
+		t.Fatalf("ServerTLSConfig() error = %v", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS13 || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("MinVersion = %x, ClientAuth = %v", tlsConfig.MinVersion, tlsConfig.ClientAuth)
+	}
+	// Each handshake gets the client CAs loaded last
+	handshake, err := tlsConfig.GetConfigForClient(nil)
+	if err != nil || handshake.ClientCAs == nil || handshake.GetConfigForClient != nil {
+		t.Errorf("GetConfigForClient() = %v, %v, want the client CAs", handshake, err)
+	}
+
+	cfg.ClientAuth = "sometimes"
+	if _, err := r.ServerTLSConfig(); err == nil {
+		t.Error("ServerTLSConfig() of an unsupported client auth mode error = nil")
+	}
+}
+
+func TestParseClientAuth(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    tls.ClientAuthType
+		wantErr bool
+	}{
+		{mode: "", want: tls.NoClientCert},
+		{mode: "none", want: tls.NoClientCert},
+		{mode: "request", want: tls.RequestClientCert},
+		{mode: "require", want: tls.RequireAnyClientCert},
+		{mode: "verify_if_given", want: tls.VerifyClientCertIfGiven},
+		{mode: "require_and_verify", want: tls.RequireAndVerifyClientCert},
+		{mode: "always", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := ParseClientAuth(tt.mode)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseClientAuth(%q) = %v, %v, want %v", tt.mode, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{version: "", want: tls.VersionTLS12},
+		{version: "1.2", want: tls.VersionTLS12},
+		{version: "1.3", want: tls.VersionTLS13},
+		{version: "1.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := ParseVersion(tt.version)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseVersion(%q) = %x, %v, want %x", tt.version, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// newConfigCommand creates the config command and its subcommands
+func newConfigCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	cmd.AddCommand(
+		newConfigPrintCommand(opts),
+		newConfigValidateCommand(opts),
+		newConfigSchemaCommand(),
+		newConfigExplainCommand(opts),
+	)
+	return cmd
+}
+
+// newConfigPrintCommand creates the config print command, which writes the
+// effective configuration with secrets redacted
+func newConfigPrintCommand(opts *options) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "print",
+		Short: "Print the effective configuration with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := opts.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			redacted := cfg.Redacted()
+			switch format {
+			case "yaml":
+				encoder := yaml.NewEncoder(os.Stdout)
+				encoder.SetIndent(2)
+				if err := encoder.Encode(redacted); err != nil {
+					return fmt.Errorf("failed to encode config: %w", err)
+				}
+				return encoder.Close()
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(redacted); err != nil {
+					return fmt.Errorf("failed to encode config: %w", err)
+				}
+				return nil
+			default:
+				return fmt.Errorf("unknown format %q, expected yaml or json", format)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "yaml", "output format: yaml or json")
+	return cmd
+}
+
+// newConfigValidateCommand creates the config validate command, which fails
+// when the configuration cannot be loaded or is invalid, listing every
+// invalid or unknown key
+func newConfigValidateCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := opts.loadConfig()
+			var validationErr *config.ValidationError
+			if errors.As(err, &validationErr) {
+				for _, fieldErr := range validationErr.Errors {
+					fmt.Fprintf(os.Stderr, "  %s\n", fieldErr)
+				}
+				return fmt.Errorf("configuration has %d errors", len(validationErr.Errors))
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Println("Configuration is valid")
+			return nil
+		},
+	}
+}
+
+// newConfigSchemaCommand creates the config schema command, which writes the
+// JSON Schema of the config file
+func newConfigSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(config.JSONSchema()); err != nil {
+				return fmt.Errorf("failed to encode schema: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// newConfigExplainCommand creates the config explain command, which shows the
+// value every source set for a key and the keys below it, the effective value
+// being the last one of each key
+func newConfigExplainCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain [KEY]",
+		Short: "Show which source set each value of a key",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var key string
+			if len(args) > 0 {
+				key = args[0]
+			}
+			origins, err := config.Explain(context.Background(), opts.logger, opts.loadOptions(), key)
+			if err != nil {
+				return err
+			}
+			if len(origins) == 0 {
+				return fmt.Errorf("no source sets %s", key)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tSOURCE\tVALUE\tEFFECTIVE")
+			for i, origin := range origins {
+				effective := i == len(origins)-1 || origins[i+1].Key != origin.Key
+				fmt.Fprintf(w, "%s\t%s\t%v\t%t\n", origin.Key, origin.Source, formatValue(origin.Value), effective)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// formatValue prints strings quoted so empty values stay visible
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(value)
+}
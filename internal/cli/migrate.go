@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/database"
+	"github.com/Gambitier/voidkitgo/internal/database/migrations"
+	"github.com/spf13/cobra"
+)
+
+// newMigrateCommand creates the migrate command and its subcommands
+func newMigrateCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema migrations",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply every pending migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runMigrator(opts, "up", func(ctx context.Context, m *database.Migrator) (int, error) {
+					return m.Up(ctx)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "down [N]",
+			Short: "Revert the last N applied migrations, 1 by default",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				n := 1
+				if len(args) > 0 {
+					var err error
+					if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+						return fmt.Errorf("invalid number of migrations %q", args[0])
+					}
+				}
+				return runMigrator(opts, "down", func(ctx context.Context, m *database.Migrator) (int, error) {
+					return m.Down(ctx, n)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "to VERSION",
+			Short: "Apply or revert migrations until VERSION is the latest applied, 0 reverts all",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				version, err := strconv.ParseInt(args[0], 10, 64)
+				if err != nil || version < 0 {
+					return fmt.Errorf("invalid version %q", args[0])
+				}
+				return runMigrator(opts, "to", func(ctx context.Context, m *database.Migrator) (int, error) {
+					return m.To(ctx, version)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List migrations and whether they are applied",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var statuses []database.MigrationStatus
+				err := withMigrator(opts, func(ctx context.Context, m *database.Migrator) error {
+					var err error
+					statuses, err = m.Status(ctx)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+				return printMigrationStatus(statuses)
+			},
+		},
+		&cobra.Command{
+			Use:   "create NAME",
+			Short: "Write empty up and down files for a new migration",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cfg, err := opts.loadConfig()
+				if err != nil {
+					return err
+				}
+				paths, err := database.CreateMigration(cfg.Database.Migrations.Dir, args[0], time.Now())
+				for _, path := range paths {
+					fmt.Println(path)
+				}
+				return err
+			},
+		},
+	)
+	return cmd
+}
+
+// runMigrator runs a migration command and logs how many migrations it changed
+func runMigrator(opts *options, command string, fn func(ctx context.Context, m *database.Migrator) (int, error)) error {
+	return withMigrator(opts, func(ctx context.Context, m *database.Migrator) error {
+		count, err := fn(ctx, m)
+		if err != nil {
+			return err
+		}
+		opts.logger.Infof("Migrate %s completed, %d migrations changed", command, count)
+		return nil
+	})
+}
+
+// withMigrator connects to the configured database and runs fn with a
+// migrator of the embedded migrations
+func withMigrator(opts *options, fn func(ctx context.Context, m *database.Migrator) error) error {
+	cfg, err := opts.loadConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Database.Enabled {
+		return errors.New("database is not enabled in the configuration")
+	}
+
+	db, err := database.New(&cfg.Database, opts.logger)
+	if err != nil {
+		return err
+	}
+	defer db.Pool().Close()
+
+	migrator, err := database.NewMigrator(db, migrations.FS, cfg.Database.Migrations.Table, opts.logger)
+	if err != nil {
+		return err
+	}
+
+	// Interrupting cancels the running migration, which is rolled back
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return fn(ctx, migrator)
+}
+
+// printMigrationStatus writes the migrations as a table to stdout
+func printMigrationStatus(statuses []database.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt, name := "pending", "", status.Name
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Missing {
+			state, name = "missing", "(file not found)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, name, state, appliedAt)
+	}
+	return w.Flush()
+}
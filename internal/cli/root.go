@@ -0,0 +1,109 @@
+// Package cli implements the command line interface of the server binary
+package cli
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/Gambitier/voidkitgo/internal/buildinfo"
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// options holds the flags shared by every command and the logger, which
+// starts as a bootstrap logger and is replaced once the config is loaded
+type options struct {
+	configPaths []string
+	configDir   string
+	env         string
+	remote      string
+	set         []string
+	logger      *logrus.Logger
+}
+
+// Execute runs the command selected by the process arguments
+func Execute() (err error) {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	opts := &options{logger: logger}
+
+	// Add panic recovery for the main thread
+	defer func() {
+		if r := recover(); r != nil {
+			opts.logger.Errorf("Recovered from panic in main thread: %v\nStack trace:\n%s", r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if err := NewRootCommand(opts).Execute(); err != nil {
+		opts.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NewRootCommand creates the root command, which serves when no subcommand is
+// given so existing invocations keep working
+func NewRootCommand(opts *options) *cobra.Command {
+	serve := newServeCommand(opts)
+	root := &cobra.Command{
+		Use:           "voidkitgo",
+		Short:         "HTTP and gRPC service",
+		Args:          cobra.NoArgs,
+		RunE:          serve.RunE,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringArrayVar(&opts.configPaths, "config", []string{"default.yaml"}, "path to a config file, repeat to merge several in order")
+	flags.StringVar(&opts.configDir, "config-dir", "", "directory of config files merged after --config (default conf.d next to the first config file)")
+	flags.StringVar(&opts.env, "env", string(config.Development), "environment")
+	flags.StringVar(&opts.remote, "remote-config", "", "key-value store merged after the config files: consul://host:8500/prefix or file:///path/kv.yaml")
+	flags.StringArrayVar(&opts.set, "set", nil, "override a config key, like --set server.http.port=9090")
+
+	root.AddCommand(
+		serve,
+		newConfigCommand(opts),
+		newVersionCommand(),
+		newHealthcheckCommand(opts),
+		newRoutesCommand(opts),
+		newMigrateCommand(opts),
+	)
+	return root
+}
+
+// loadOptions returns the config sources selected by the flags
+func (o *options) loadOptions() config.LoadOptions {
+	return config.LoadOptions{
+		Files:  o.configPaths,
+		Env:    o.env,
+		Dir:    o.configDir,
+		Remote: o.remote,
+		Set:    o.set,
+	}
+}
+
+// loadConfig loads the configuration and replaces the bootstrap logger with
+// the one described by it
+func (o *options) loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(context.Background(), o.logger, o.loadOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	info := buildinfo.Get()
+	logger, err := logging.New(&cfg.Logging, logrus.Fields{
+		"env":     cfg.Server.Env,
+		"version": info.Version,
+		"commit":  info.Commit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+	o.logger = logger
+
+	return cfg, nil
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Gambitier/voidkitgo/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// newRoutesCommand creates the routes command, which lists every HTTP route
+// and gRPC method the server registers with the configuration
+func newRoutesCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "routes",
+		Short: "List the HTTP routes and gRPC methods",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := opts.loadConfig()
+			if err != nil {
+				return err
+			}
+			routes, err := server.Routes(cfg, opts.logger)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SERVER\tMETHOD\tPATH\tTARGET\tPUBLIC")
+			for _, route := range routes {
+				method := route.Method
+				if method == "" {
+					method = "-"
+				}
+				target := route.Target
+				if target == "" {
+					target = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", route.Server, method, route.Path, target, route.Public)
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/server"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newServeCommand creates the serve command, which runs the server until it
+// receives an interrupt or termination signal
+func newServeCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP and gRPC servers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := opts.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			// Create server instance
+			srv := server.NewServer(cfg, opts.logger)
+			if cfg.Server.Reload.Enabled {
+				srv.WatchConfig(config.NewWatcher(config.WatcherParams{
+					Logger:  opts.logger,
+					Options: opts.loadOptions(),
+					Config:  cfg,
+				}))
+			}
+
+			// Create context that listens for the interrupt signal from the OS, this is
+			// the only place signals are handled and the server shuts down when ctx is done
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// Signals are also counted from the start, so a second one arriving
+			// right after the first is not missed
+			signals := make(chan os.Signal, 2)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(signals)
+			go forceExitOnSecondSignal(signals, opts.logger, os.Exit)
+
+			return srv.Start(ctx)
+		},
+	}
+}
+
+// forceExitOnSecondSignal calls exit, os.Exit outside tests, if another signal
+// arrives while the server is already shutting down gracefully. The first
+// signal of signals is the one starting the shutdown.
+func forceExitOnSecondSignal(signals <-chan os.Signal, logger *logrus.Logger, exit func(code int)) {
+	<-signals
+	sig := <-signals
+
+	logger.Errorf("Received second signal %v during shutdown, forcing exit", sig)
+	exit(1)
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestForceExitOnSecondSignal(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	signals := make(chan os.Signal, 2)
+	exited := make(chan int, 1)
+	go forceExitOnSecondSignal(signals, logger, func(code int) { exited <- code })
+
+	// The first signal starts the graceful shutdown
+	signals <- os.Interrupt
+	select {
+	case code := <-exited:
+		t.Fatalf("exit(%d) after the first signal, want the graceful shutdown", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	signals <- syscall.SIGTERM
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("exit code = %d, want 1", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no exit after the second signal")
+	}
+}
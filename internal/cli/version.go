@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Gambitier/voidkitgo/internal/buildinfo"
+	"github.com/spf13/cobra"
+)
+
+// newVersionCommand creates the version command, which prints the build
+// information injected at build time
+func newVersionCommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the build information",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := buildinfo.Get()
+			switch format {
+			case "text":
+				fmt.Printf("voidkitgo %s\n", info)
+				return nil
+			case "json":
+				return json.NewEncoder(os.Stdout).Encode(info)
+			default:
+				return fmt.Errorf("unknown format %q, expected text or json", format)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", "text", "output format: text or json")
+	return cmd
+}
@@ -1,22 +1,27 @@
 package config
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/sirupsen/logrus"
 
 	"github.com/spf13/viper"
 )
 
-// Environment represents the deployment environment
+// Environment represents the deployment environment. Besides the built-in
+// environments, custom ones are declared under environments in the config.
 type Environment string
 
 const (
 	Development Environment = "development"
+	Test        Environment = "test"
+	Staging     Environment = "staging"
 	Production  Environment = "production"
 )
 
@@ -28,115 +33,578 @@ func (env Environment) IsProduction() bool {
 	return env == Production
 }
 
-// Config represents the service configuration
+// Profile declares the behavior enabled in an environment
+type Profile struct {
+	// Reflection registers the gRPC server reflection service
+	Reflection bool `mapstructure:"reflection"`
+	// Pprof serves the runtime profiles under /debug/pprof/ with the admin endpoints
+	Pprof bool `mapstructure:"pprof"`
+	// DebugEndpoints serves the redacted running config under /debug/config
+	// with the admin endpoints
+	DebugEndpoints bool `mapstructure:"debug_endpoints"`
+	// VerboseErrors returns panic and internal error messages to clients,
+	// which otherwise get a generic message
+	VerboseErrors bool       `mapstructure:"verbose_errors"`
+	CORS          CORSConfig `mapstructure:"cors"`
+}
+
+// CORSConfig holds the cross-origin policy of the HTTP server
+type CORSConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// AllowedOrigins are the origins allowed to call the API, * allows any
+	AllowedOrigins []string `mapstructure:"allowed_origins" validate:"required_if=Enabled true"`
+	// AllowedMethods defaults to GET, POST, PUT, PATCH and DELETE
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+	// AllowedHeaders defaults to the headers requested by the preflight
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
+	// AllowCredentials lets browsers send cookies and authorization headers
+	AllowCredentials bool `mapstructure:"allow_credentials"`
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration `mapstructure:"max_age" validate:"gte=0"`
+}
+
+// defaultProfiles returns the profiles of the built-in environments, the
+// environments of the config override them field by field
+func defaultProfiles() map[Environment]Profile {
+	return map[Environment]Profile{
+		Development: {
+			Reflection:     true,
+			Pprof:          true,
+			DebugEndpoints: true,
+			VerboseErrors:  true,
+			CORS: CORSConfig{
+				Enabled:        true,
+				AllowedOrigins: []string{"*"},
+				MaxAge:         10 * time.Minute,
+			},
+		},
+		Test: {
+			Reflection:    true,
+			VerboseErrors: true,
+		},
+		Staging: {
+			Reflection: true,
+			Pprof:      true,
+		},
+		Production: {},
+	}
+}
+
+// ListenMode controls how the HTTP and gRPC servers are exposed
+type ListenMode string
+
+const (
+	// ListenModeSplit binds HTTP and gRPC to their own ports
+	ListenModeSplit ListenMode = "split"
+	// ListenModeSingle multiplexes HTTP and gRPC over a single port
+	ListenModeSingle ListenMode = "single"
+)
+
+func (mode ListenMode) IsSingle() bool {
+	return mode == ListenModeSingle
+}
+
+// Config represents the service configuration. Fields tagged reload:"hot"
+// may change while the server runs, see Watcher.
 type Config struct {
-	Server  ServerConfig  `mapstructure:"server"`
-	Logging LoggingConfig `mapstructure:"logging"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Authz     AuthzConfig     `mapstructure:"authz"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Cache     CacheConfig     `mapstructure:"cache"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	// Features are named feature flags, read through the features package
+	Features map[string]bool `mapstructure:"features" reload:"hot"`
+	// Environments holds the profile of every environment server.environment
+	// may name, the built-in ones being development, test, staging and production
+	Environments map[string]Profile `mapstructure:"environments" validate:"dive"`
+}
+
+// Profile returns the profile of the configured environment
+func (c *Config) Profile() Profile {
+	return c.Environments[string(c.Server.Env)]
 }
 
 // ServerConfig holds the server-specific configuration
 type ServerConfig struct {
-	HTTP HTTPConfig  `mapstructure:"http"`
-	GRPC GRPCConfig  `mapstructure:"grpc"`
-	Env  Environment `mapstructure:"environment"`
+	HTTP     HTTPConfig     `mapstructure:"http"`
+	GRPC     GRPCConfig     `mapstructure:"grpc"`
+	Listen   ListenConfig   `mapstructure:"listen"`
+	Admin    AdminConfig    `mapstructure:"admin"`
+	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Shutdown ShutdownConfig `mapstructure:"shutdown"`
+	Reload   ReloadConfig   `mapstructure:"reload"`
+	// Env selects the profile of environments, --env by default
+	Env Environment `mapstructure:"environment"`
+}
+
+// ReloadConfig holds the configuration hot reload settings
+type ReloadConfig struct {
+	// Enabled watches the config files and reloads them on change or SIGHUP
+	Enabled bool `mapstructure:"enabled" default:"true"`
+}
+
+// AdminConfig holds the admin endpoints configuration
+type AdminConfig struct {
+	// Port serves the admin endpoints and metrics on a separate listener,
+	// when zero they are served by the HTTP server
+	Port int `mapstructure:"port" validate:"gte=0"`
+	// Host is the interface the admin listener binds, loopback by default.
+	// Without auth, only the metrics and the log level are served when it
+	// accepts remote connections.
+	Host string `mapstructure:"host" default:"127.0.0.1" validate:"omitempty,hostname|ip"`
+}
+
+// MetricsConfig holds the Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled" default:"true"`
+	Path    string `mapstructure:"path" default:"/metrics" validate:"required_if=Enabled true,omitempty,startswith=/"`
+}
+
+// ListenConfig holds the listener configuration
+type ListenConfig struct {
+	// Mode is either split (separate HTTP and gRPC ports) or single
+	Mode ListenMode `mapstructure:"mode" default:"split" validate:"omitempty,oneof=split single"`
+	// Port is the shared port used in single mode
+	Port int `mapstructure:"port" validate:"required_if=Mode single"`
+}
+
+// ShutdownConfig holds graceful shutdown configuration
+type ShutdownConfig struct {
+	// DrainTimeout bounds the whole shutdown once listeners start closing
+	DrainTimeout time.Duration `mapstructure:"drain_timeout" default:"30s" validate:"gte=0"`
+	// PreStopDelay is how long the server reports not ready before closing
+	// listeners, giving load balancers time to stop routing to it
+	PreStopDelay time.Duration `mapstructure:"pre_stop_delay" validate:"gte=0"`
 }
 
 // HTTPConfig holds HTTP server configuration
 type HTTPConfig struct {
-	Port         int           `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Port         int           `mapstructure:"port" default:"8085"`
+	ReadTimeout  time.Duration `mapstructure:"read_timeout" default:"5s"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout" default:"5s"`
+	IdleTimeout  time.Duration `mapstructure:"idle_timeout" default:"120s"`
+	// MaxBodyBytes bounds the request bodies of transcoded gRPC calls, zero
+	// removes the limit
+	MaxBodyBytes int64     `mapstructure:"max_body_bytes" default:"4194304" validate:"gte=0"`
+	TLS          TLSConfig `mapstructure:"tls"`
 }
 
 // GRPCConfig holds gRPC server configuration
 type GRPCConfig struct {
-	Port int `mapstructure:"port"`
+	Port int       `mapstructure:"port" default:"8086"`
+	TLS  TLSConfig `mapstructure:"tls"`
+}
+
+// TLSConfig holds the TLS configuration of a listener, files are reloaded
+// when they change on disk
+type TLSConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertFile string `mapstructure:"cert_file" validate:"required_if=Enabled true"`
+	KeyFile  string `mapstructure:"key_file" validate:"required_if=Enabled true"`
+	// CAFile holds the CAs client certificates are verified against
+	CAFile string `mapstructure:"ca_file" validate:"required_if=ClientAuth verify_if_given,required_if=ClientAuth require_and_verify"`
+	// MinVersion is the minimum TLS version: 1.2 or 1.3
+	MinVersion string `mapstructure:"min_version" validate:"omitempty,oneof=1.2 1.3"`
+	// ClientAuth is the client certificate policy: none, request, require,
+	// verify_if_given or require_and_verify
+	ClientAuth string `mapstructure:"client_auth" validate:"omitempty,oneof=none request require verify_if_given require_and_verify"`
 }
 
 // LoggingConfig represents the logging configuration
 type LoggingConfig struct {
-	Level  string `mapstructure:"level" validate:"required,oneof=debug info warn error"`
-	Format string `mapstructure:"format" validate:"required,oneof=json text"`
+	Level  string `mapstructure:"level" default:"info" validate:"required,oneof=debug info warn error" reload:"hot"`
+	Format string `mapstructure:"format" default:"json" validate:"required,oneof=json text"`
+	// Output is where entries are written: stdout, stderr or file
+	Output string        `mapstructure:"output" default:"stdout" validate:"omitempty,oneof=stdout stderr file"`
+	File   LogFileConfig `mapstructure:"file"`
+	// ReportCaller adds the calling function and file to every entry
+	ReportCaller bool `mapstructure:"report_caller"`
+	// Fields are static fields added to every entry, such as service
+	Fields map[string]string `mapstructure:"fields"`
+}
+
+// LogFileConfig holds the log file and rotation configuration
+type LogFileConfig struct {
+	Path       string `mapstructure:"path"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb" default:"100" validate:"gte=0"`
+	MaxBackups int    `mapstructure:"max_backups" default:"5" validate:"gte=0"`
+	MaxAgeDays int    `mapstructure:"max_age_days" default:"30" validate:"gte=0"`
+	Compress   bool   `mapstructure:"compress"`
+}
+
+// TracingConfig holds the OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	ServiceName string `mapstructure:"service_name" validate:"required_if=Enabled true"`
+	// Exporter is where spans are sent: otlp, stdout or file
+	Exporter string `mapstructure:"exporter" default:"otlp" validate:"omitempty,oneof=otlp stdout file"`
+	// SampleRatio is the fraction of new traces that are sampled, the decision
+	// of an incoming parent span is always honored
+	SampleRatio float64    `mapstructure:"sample_ratio" default:"1.0" validate:"gte=0,lte=1"`
+	OTLP        OTLPConfig `mapstructure:"otlp"`
+	// File is the path spans are written to with the file exporter
+	File string `mapstructure:"file"`
+}
+
+// OTLPConfig holds the OTLP gRPC exporter configuration
+type OTLPConfig struct {
+	Endpoint string            `mapstructure:"endpoint" default:"localhost:4317"`
+	Insecure bool              `mapstructure:"insecure"`
+	Timeout  time.Duration     `mapstructure:"timeout" default:"10s" validate:"gte=0"`
+	Headers  map[string]string `mapstructure:"headers"`
+}
+
+// AuthConfig holds the bearer token authentication configuration
+type AuthConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Algorithms are the accepted JWT signing algorithms
+	Algorithms []string `mapstructure:"algorithms" default:"RS256" validate:"required_if=Enabled true,dive,oneof=HS256 HS384 HS512 RS256 RS384 RS512 PS256 PS384 PS512 ES256 ES384 ES512"`
+	// Secret is the shared key used by the HMAC algorithms
+	Secret Secret     `mapstructure:"secret"`
+	JWKS   JWKSConfig `mapstructure:"jwks"`
+	// Issuer and Audience are checked against the iss and aud claims when set
+	Issuer   string `mapstructure:"issuer"`
+	Audience string `mapstructure:"audience"`
+	// Leeway tolerates clock skew when checking exp, nbf and iat
+	Leeway time.Duration `mapstructure:"leeway" validate:"gte=0"`
+	// RolesClaim and ScopesClaim are the claim paths roles and scopes are read
+	// from, nested claims use dots such as realm_access.roles
+	RolesClaim  string `mapstructure:"roles_claim" default:"roles"`
+	ScopesClaim string `mapstructure:"scopes_claim" default:"scope"`
+}
+
+// JWKSConfig holds where the public keys of asymmetric algorithms are loaded from
+type JWKSConfig struct {
+	// File is a local JWKS document, it takes precedence over URL
+	File string `mapstructure:"file"`
+	URL  string `mapstructure:"url" validate:"omitempty,url"`
+	// RefreshInterval is how long fetched keys are cached
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" default:"15m" validate:"gte=0"`
+	Timeout         time.Duration `mapstructure:"timeout" default:"5s" validate:"gte=0"`
+}
+
+// AuthzConfig holds the authorization policies of gRPC methods and HTTP routes,
+// policies declared in config take precedence over proto method options
+type AuthzConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DefaultPolicy applies to methods and routes without a policy: allow or deny
+	DefaultPolicy string       `mapstructure:"default_policy" default:"allow" validate:"omitempty,oneof=allow deny"`
+	Rules         []PolicyRule `mapstructure:"rules" validate:"dive"`
+}
+
+// PolicyRule declares who may call a gRPC method or an HTTP route, every
+// condition that is set must hold
+type PolicyRule struct {
+	// Method is a full gRPC method name such as /common.v1.CommonService/HealthCheck,
+	// or /common.v1.CommonService/* for every method of a service
+	Method string `mapstructure:"method" validate:"required_without=Route,excluded_with=Route"`
+	// Route is an HTTP method and route template such as "PUT /admin/log-level"
+	Route string `mapstructure:"route"`
+	// Roles grants access to callers holding any of the roles
+	Roles []string `mapstructure:"roles"`
+	// Scopes grants access to tokens issued with all of the scopes
+	Scopes []string `mapstructure:"scopes"`
+	// Expr is an expression such as "has_role('admin') || claims.tenant == 'acme'"
+	Expr string `mapstructure:"expr"`
+}
+
+// RateLimitConfig holds the rate and concurrency limits of both servers
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Backend keeps the token buckets: memory (per instance) or redis (shared)
+	Backend string `mapstructure:"backend" default:"memory" validate:"omitempty,oneof=memory redis"`
+	// Key identifies the client owning a bucket: ip, principal or api_key,
+	// requests without a principal or API key are keyed by ip
+	Key          string `mapstructure:"key" default:"ip" validate:"omitempty,oneof=ip principal api_key"`
+	APIKeyHeader string `mapstructure:"api_key_header" default:"X-API-Key"`
+	// TrustForwardedFor keys clients by the first X-Forwarded-For address,
+	// only enable it behind a proxy that sets the header
+	TrustForwardedFor bool `mapstructure:"trust_forwarded_for"`
+	// Rate is the number of requests per second allowed per client, Burst
+	// the number of requests allowed at once, at least 1 when Rate is set
+	Rate      float64             `mapstructure:"rate" default:"50" validate:"gte=0" reload:"hot"`
+	Burst     int                 `mapstructure:"burst" default:"100" validate:"gte=0" reload:"hot"`
+	Overrides []RateLimitOverride `mapstructure:"overrides" validate:"dive" reload:"hot"`
+	// MaxInFlight bounds the concurrent requests across both servers, zero is unlimited
+	MaxInFlight int `mapstructure:"max_in_flight" validate:"gte=0"`
+	// KeyPrefix namespaces the buckets of the redis backend, which uses the
+	// Redis connection of the cache
+	KeyPrefix string `mapstructure:"key_prefix" default:"voidkitgo:ratelimit:"`
+}
+
+// RateLimitOverride sets the limit of a gRPC method or HTTP route, each
+// overridden method or route gets its own bucket. A zero rate disables limiting,
+// a positive rate requires a burst of at least 1.
+type RateLimitOverride struct {
+	// Method is a full gRPC method name, or /package.Service/* for every method of a service
+	Method string `mapstructure:"method" validate:"required_without=Route,excluded_with=Route"`
+	// Route is an HTTP method and route template such as "GET /v1/health"
+	Route string  `mapstructure:"route"`
+	Rate  float64 `mapstructure:"rate" validate:"gte=0"`
+	Burst int     `mapstructure:"burst" validate:"gte=0"`
+}
+
+// CacheConfig holds the shared cache and Redis connection configuration
+type CacheConfig struct {
+	// Type is the cache backend: redis, shared by the replicas, or memory,
+	// held by each process and suited to development and tests
+	Type string `mapstructure:"type" default:"redis" validate:"required,oneof=redis memory"`
+	Host string `mapstructure:"host" validate:"required_if=Type redis"`
+	// Port is a string so it can hold environment placeholders
+	Port     string `mapstructure:"port" validate:"required_if=Type redis"`
+	Password Secret `mapstructure:"password"`
+	DB       int    `mapstructure:"db" validate:"gte=0"`
+	// KeyPrefix namespaces every cache key
+	KeyPrefix string `mapstructure:"key_prefix" default:"voidkitgo:cache:"`
+	// DefaultTTL applies to entries stored without a TTL, zero never expires
+	DefaultTTL time.Duration `mapstructure:"default_ttl" default:"5m" validate:"gte=0"`
+	// MaxEntries bounds the memory cache of each process, which evicts the
+	// least recently used entries when full. Zero is unbounded and lets the
+	// cache grow with the keys stored.
+	MaxEntries  int           `mapstructure:"max_entries" default:"10000" validate:"gte=0"`
+	PoolSize    int           `mapstructure:"pool_size" default:"10" validate:"gte=0"`
+	DialTimeout time.Duration `mapstructure:"dial_timeout" default:"5s" validate:"gte=0"`
+}
+
+// DatabaseConfig holds the PostgreSQL connection pool configuration
+type DatabaseConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host" validate:"required_if=Enabled true"`
+	// Port is a string so it can hold environment placeholders
+	Port string `mapstructure:"port" validate:"required_if=Enabled true"`
+	User string `mapstructure:"user" validate:"required_if=Enabled true"`
+	// Password may be a secret reference, like file:///run/secrets/db_password
+	Password Secret `mapstructure:"password"`
+	Name     string `mapstructure:"name" validate:"required_if=Enabled true"`
+	SSLMode  string `mapstructure:"ssl_mode" default:"disable" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	// Params are additional connection parameters, such as application_name
+	Params map[string]string `mapstructure:"params"`
+	// MaxConns bounds the pool size, MinConns connections are kept open
+	MaxConns        int32         `mapstructure:"max_conns" default:"10" validate:"gte=0"`
+	MinConns        int32         `mapstructure:"min_conns" validate:"gte=0,ltefield=MaxConns"`
+	MaxConnLifetime time.Duration `mapstructure:"max_conn_lifetime" default:"1h" validate:"gte=0"`
+	MaxConnIdleTime time.Duration `mapstructure:"max_conn_idle_time" default:"30m" validate:"gte=0"`
+	ConnectTimeout  time.Duration `mapstructure:"connect_timeout" default:"5s" validate:"gte=0"`
+	// StatementTimeout aborts statements running longer, zero disables it
+	StatementTimeout time.Duration `mapstructure:"statement_timeout" default:"30s" validate:"gte=0"`
+
+	Migrations MigrationsConfig `mapstructure:"migrations"`
+}
+
+// MigrationsConfig holds the schema migrations configuration
+type MigrationsConfig struct {
+	// Table records the applied migration versions
+	Table string `mapstructure:"table" default:"schema_migrations" validate:"required"`
+	// Dir is where the create subcommand writes new migration files, the
+	// server runs the migrations embedded at build time
+	Dir string `mapstructure:"dir" default:"internal/database/migrations" validate:"required"`
+	// OnStart applies pending migrations before the servers start, meant for
+	// development environments
+	OnStart bool `mapstructure:"on_start"`
+	// Timeout bounds how long migrations may take on start
+	Timeout time.Duration `mapstructure:"timeout" default:"5m" validate:"gt=0"`
 }
 
-// LoadConfig loads and validates the configuration
+// LoadConfig loads and validates the configuration of a single file, merged
+// with its environment overlay, see Load
 func LoadConfig(logger *logrus.Logger, relConfigPath string, env string) (*Config, error) {
-	// Get the absolute path to the config file
-	configPath, err := filepath.Abs(relConfigPath)
+	return Load(context.Background(), logger, LoadOptions{Files: []string{relConfigPath}, Env: env})
+}
+
+// Load loads and validates the configuration, merging the sources selected by
+// opts in precedence order. ${VAR} or ${VAR:-default} placeholders in the
+// values of every source are expanded, variables are also read from a .env
+// file next to the first config file without overriding those already set.
+func Load(ctx context.Context, logger *logrus.Logger, opts LoadOptions) (*Config, error) {
+	merged, err := mergeSources(ctx, logger, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path for config file: %w", err)
+		return nil, err
+	}
+	if len(merged.unresolved) > 0 {
+		return nil, &UnresolvedError{Placeholders: merged.unresolved}
 	}
 
-	// Get the directory of the config file
-	configDir := filepath.Dir(configPath)
-	configName := filepath.Base(configPath)
-	configExt := filepath.Ext(configPath)
-	configNameWithoutExt := configName[:len(configName)-len(configExt)]
-
-	// Initialize viper
+	// Unmarshal the config
 	v := viper.New()
-	v.SetConfigName(configNameWithoutExt)
-	v.SetConfigType("yaml")
-	v.AddConfigPath(configDir)
-
-	// Set defaults
-	setDefaults(v)
-
-	// Try to read environment-specific config
-	envConfigPath := filepath.Join(configDir, fmt.Sprintf("%s.%s%s", configNameWithoutExt, env, configExt))
-	if _, err := os.Stat(envConfigPath); err == nil {
-		v.SetConfigName(fmt.Sprintf("%s.%s", configNameWithoutExt, env))
-		if err := v.MergeInConfig(); err != nil {
-			return nil, fmt.Errorf("failed to merge environment config: %w", err)
-		}
+	if err := v.MergeConfigMap(merged.values); err != nil {
+		return nil, fmt.Errorf("failed to merge config: %w", err)
+	}
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	logger.Infof("Using config file: %s", envConfigPath)
+	// Resolve secret references once every value is known
+	if err := resolveSecrets(ctx, &config); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
 
-	// Read the default config
-	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	// Validate the config, reporting unknown keys along with invalid values
+	fieldErrors := append(merged.fieldErrors, validateConfig(&config)...)
+	fieldErrors = append(fieldErrors, validateEnvironment(&config, opts.Env)...)
+	if len(fieldErrors) > 0 {
+		return nil, fmt.Errorf("config validation failed: %w", &ValidationError{Errors: fieldErrors})
 	}
 
-	// Unmarshal the config
+	return &config, nil
+}
+
+// LoadEnv decodes the defaults of the config, overridden by the VOIDKIT_
+// environment variables and the Set overrides of opts. Files and the remote
+// store are not read, secrets are not resolved and the result is not
+// validated, so commands needing a few settings, like the healthcheck, stay
+// cheap and quiet.
+func LoadEnv(opts LoadOptions) (*Config, error) {
+	merged := map[string]any{}
+	for _, source := range []Source{defaultsSource{env: opts.Env}, envSource{}, setSource(opts.Set)} {
+		values, err := source.Load(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", source.Name(), err)
+		}
+		mergeValues(merged, values)
+	}
+
+	v := viper.New()
+	if err := v.MergeConfigMap(merged); err != nil {
+		return nil, fmt.Errorf("failed to merge config: %w", err)
+	}
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	return &config, nil
+}
+
+// Origin is a value a source set for a config key
+type Origin struct {
+	Key    string
+	Source string
+	Value  any
+}
 
-	// Validate the config
-	if err := validateConfig(&config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+// Explain returns the values set for key and the keys below it by every
+// source, sorted by key and in precedence order, so the last origin of a key
+// holds its effective value. Secrets are redacted unless they are references.
+// The configuration does not need to be valid.
+func Explain(ctx context.Context, logger *logrus.Logger, opts LoadOptions, key string) ([]Origin, error) {
+	merged, err := mergeSources(ctx, logger, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return &config, nil
+	key = strings.ToLower(key)
+	var keys []string
+	for k := range merged.origins {
+		if key == "" || k == key || strings.HasPrefix(k, key+".") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var origins []Origin
+	for _, k := range keys {
+		secret := keyType(k) == reflect.TypeOf(Secret(""))
+		for _, origin := range merged.origins[k] {
+			if secret {
+				origin.Value = redactSecret(fmt.Sprint(origin.Value))
+			}
+			origins = append(origins, origin)
+		}
+	}
+	return origins, nil
 }
 
-// setDefaults sets default values for configuration
-func setDefaults(v *viper.Viper) {
-	// API defaults
-	v.SetDefault("api.http_port", 8085)
-	v.SetDefault("api.grpc_port", 8086)
-	v.SetDefault("api.read_timeout", "5s")
-	v.SetDefault("api.write_timeout", "5s")
-	v.SetDefault("api.idle_timeout", "120s")
+// mergedSources holds the values of every source merged in precedence order
+type mergedSources struct {
+	values map[string]any
+	// origins holds the values set by each source for every leaf key
+	origins     map[string][]Origin
+	unresolved  map[string][]string
+	fieldErrors []FieldError
+}
+
+// mergeSources loads the sources of opts and merges their values
+func mergeSources(ctx context.Context, logger *logrus.Logger, opts LoadOptions) (*mergedSources, error) {
+	if len(opts.Files) > 0 {
+		// Load the .env file before placeholders and overrides are resolved
+		if err := loadDotEnv(filepath.Dir(opts.Files[0])); err != nil {
+			return nil, err
+		}
+	}
+
+	sources, err := opts.sources()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name()
+	}
+	logger.Infof("Loading config from %s", strings.Join(names, ", "))
+
+	merged := &mergedSources{
+		values:     map[string]any{},
+		origins:    map[string][]Origin{},
+		unresolved: map[string][]string{},
+	}
+	unresolvedBy := map[string]string{}
+	for _, source := range sources {
+		values, err := source.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", source.Name(), err)
+		}
 
-	// Logging defaults
-	v.SetDefault("logging.level", "info")
-	v.SetDefault("logging.format", "json")
+		for _, key := range unknownKeys("", values, reflect.TypeOf(Config{})) {
+			merged.fieldErrors = append(merged.fieldErrors, FieldError{Key: key, Message: "unknown key in " + source.Name()})
+		}
+
+		missing := map[string][]string{}
+		values = expandValue("", values, missing).(map[string]any)
+		for key, variables := range missing {
+			merged.unresolved[key] = variables
+			unresolvedBy[key] = source.Name()
+		}
+
+		flattenValues("", values, func(key string, value any) {
+			merged.origins[key] = append(merged.origins[key], Origin{Key: key, Source: source.Name(), Value: value})
+		})
+		mergeValues(merged.values, values)
+	}
+
+	// Placeholders of keys overridden by a later source do not need a value
+	for key := range merged.unresolved {
+		if origins := merged.origins[key]; len(origins) > 0 && origins[len(origins)-1].Source != unresolvedBy[key] {
+			delete(merged.unresolved, key)
+		}
+	}
+	return merged, nil
 }
 
-// validateConfig validates the configuration using struct tags
-func validateConfig(cfg *Config) error {
-	validate := validator.New()
-	if err := validate.Struct(cfg); err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			for _, e := range validationErrors {
-				return fmt.Errorf("validation failed for field %s: %s", e.Field(), e.Tag())
+// keyType returns the type of the field of a config key, nil when unknown
+func keyType(key string) reflect.Type {
+	t := reflect.TypeOf(Config{})
+	for _, part := range strings.Split(key, ".") {
+		switch t.Kind() {
+		case reflect.Struct:
+			var found bool
+			for i := 0; i < t.NumField(); i++ {
+				name, _, _ := strings.Cut(t.Field(i).Tag.Get("mapstructure"), ",")
+				if name == part {
+					t, found = t.Field(i).Type, true
+					break
+				}
+			}
+			if !found {
+				return nil
 			}
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
 		}
-		return fmt.Errorf("validation failed: %w", err)
 	}
-	return nil
+	return t
 }
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change is a config key whose value differs between two configurations
+type Change struct {
+	Key string
+	// Old and New are the values of the key with secrets redacted, nil when
+	// the key is absent
+	Old, New any
+	// Hot reports whether the key can change without a restart, which fields
+	// declare with a reload:"hot" tag applying to everything below them
+	Hot bool
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Key, c.Old, c.New)
+}
+
+// Diff returns the changes from old to new sorted by key. Struct fields and
+// map entries are compared one by one, slices as a whole.
+func Diff(old, new *Config) []Change {
+	before := map[string]diffLeaf{}
+	after := map[string]diffLeaf{}
+	flattenLeaves(reflect.ValueOf(*old), "", false, before)
+	flattenLeaves(reflect.ValueOf(*new), "", false, after)
+
+	var changes []Change
+	for key, leaf := range before {
+		next, ok := after[key]
+		if ok && reflect.DeepEqual(plainValue(leaf.value, false), plainValue(next.value, false)) {
+			continue
+		}
+		change := Change{Key: key, Old: plainValue(leaf.value, true), Hot: leaf.hot}
+		if ok {
+			change.New = plainValue(next.value, true)
+		}
+		changes = append(changes, change)
+	}
+	for key, leaf := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, Change{Key: key, New: plainValue(leaf.value, true), Hot: leaf.hot})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+type diffLeaf struct {
+	value reflect.Value
+	hot   bool
+}
+
+// flattenLeaves records every leaf value reachable from v by config key
+func flattenLeaves(v reflect.Value, key string, hot bool, out map[string]diffLeaf) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			flattenLeaves(v.Field(i), joinKey(key, name), hot || field.Tag.Get("reload") == "hot", out)
+		}
+		return
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			flattenLeaves(iter.Value(), joinKey(key, fmt.Sprint(iter.Key().Interface())), hot, out)
+		}
+		return
+	}
+	out[key] = diffLeaf{value: v, hot: hot}
+}
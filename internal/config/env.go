@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// EnvPrefix prefixes the environment variables overriding config keys,
+	// server.http.port is overridden by VOIDKIT_SERVER_HTTP_PORT
+	EnvPrefix = "VOIDKIT"
+	// DotEnvFile is loaded from the config directory for local runs
+	DotEnvFile = ".env"
+)
+
+// UnresolvedError lists the placeholders whose variables are not set and
+// have no default
+type UnresolvedError struct {
+	// Placeholders maps each config key to its unresolved variables
+	Placeholders map[string][]string
+}
+
+func (e *UnresolvedError) Error() string {
+	keys := make([]string, 0, len(e.Placeholders))
+	for key := range e.Placeholders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s (%s)", key, strings.Join(e.Placeholders[key], ", ")))
+	}
+	return "unresolved environment variables: " + strings.Join(parts, "; ")
+}
+
+// loadDotEnv sets the variables of the .env file in dir that are not already
+// set, a missing file is ignored
+func loadDotEnv(dir string) error {
+	path := filepath.Join(dir, DotEnvFile)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	if err := godotenv.Load(path); err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return nil
+}
+
+// expandValue expands the placeholders of every string in value, key being
+// the config key of value
+func expandValue(key string, value any, unresolved map[string][]string) any {
+	switch value := value.(type) {
+	case string:
+		expanded, missing := expandString(value)
+		if len(missing) > 0 {
+			unresolved[key] = append(unresolved[key], missing...)
+		}
+		return expanded
+	case map[string]any:
+		// Keys are case insensitive, they are lowercased like viper does
+		out := make(map[string]any, len(value))
+		for k, item := range value {
+			k = strings.ToLower(k)
+			out[k] = expandValue(joinKey(key, k), item, unresolved)
+		}
+		return out
+	case []any:
+		out := make([]any, len(value))
+		for i, item := range value {
+			out[i] = expandValue(fmt.Sprintf("%s[%d]", key, i), item, unresolved)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// expandString replaces ${VAR} with the value of VAR and ${VAR:-default}
+// with default when VAR is unset or empty. $${ escapes a literal ${. It
+// returns the variables that are unset and have no default.
+func expandString(s string) (string, []string) {
+	var b strings.Builder
+	var missing []string
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), missing
+		}
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start-1])
+			b.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			b.WriteString(s)
+			return b.String(), missing
+		}
+		b.WriteString(s[:start])
+
+		expr := s[start+2 : start+end]
+		name, fallback, hasFallback := strings.Cut(expr, ":-")
+		value, set := os.LookupEnv(name)
+		switch {
+		case hasFallback && value == "":
+			b.WriteString(fallback)
+		case set:
+			b.WriteString(value)
+		default:
+			missing = append(missing, name)
+		}
+		s = s[start+end+1:]
+	}
+}
+
+// EnvVar returns the environment variable overriding a config key
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testLogger returns a logger discarding its output
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// writeFile writes content to name in dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExpandString(t *testing.T) {
+	t.Setenv("TEST_HOST", "db.internal")
+	t.Setenv("TEST_EMPTY", "")
+	os.Unsetenv("TEST_UNSET")
+
+	tests := []struct {
+		in          string
+		want        string
+		wantMissing []string
+	}{
+		{in: "plain", want: "plain"},
+		{in: "${TEST_HOST}", want: "db.internal"},
+		{in: "postgres://${TEST_HOST}:5432", want: "postgres://db.internal:5432"},
+		{in: "${TEST_UNSET:-localhost}", want: "localhost"},
+		{in: "${TEST_HOST:-localhost}", want: "db.internal"},
+		// Empty variables take the default, or expand to nothing without one
+		{in: "${TEST_EMPTY:-fallback}", want: "fallback"},
+		{in: "[${TEST_EMPTY}]", want: "[]"},
+		{in: "${TEST_UNSET:-}", want: ""},
+		{in: "$${TEST_HOST}", want: "${TEST_HOST}"},
+		{in: "$${TEST_HOST} ${TEST_HOST}", want: "${TEST_HOST} db.internal"},
+		{in: "${TEST_HOST", want: "${TEST_HOST"},
+		{in: "a${TEST_UNSET}b${TEST_UNSET2}", want: "ab", wantMissing: []string{"TEST_UNSET", "TEST_UNSET2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, missing := expandString(tt.in)
+			if got != tt.want {
+				t.Errorf("expandString() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("expandString() missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestExpandValue(t *testing.T) {
+	t.Setenv("TEST_PORT", "9090")
+	os.Unsetenv("TEST_UNSET")
+
+	unresolved := map[string][]string{}
+	got := expandValue("", map[string]any{
+		"Server": map[string]any{
+			"http":  map[string]any{"port": "${TEST_PORT}", "timeout": 5},
+			"hosts": []any{"a", "${TEST_UNSET}"},
+		},
+	}, unresolved)
+
+	want := map[string]any{
+		"server": map[string]any{
+			"http":  map[string]any{"port": "9090", "timeout": 5},
+			"hosts": []any{"a", ""},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandValue() = %v, want %v", got, want)
+	}
+	wantUnresolved := map[string][]string{"server.hosts[1]": {"TEST_UNSET"}}
+	if !reflect.DeepEqual(unresolved, wantUnresolved) {
+		t.Errorf("unresolved = %v, want %v", unresolved, wantUnresolved)
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	if got := EnvVar("server.http.port"); got != "VOIDKIT_SERVER_HTTP_PORT" {
+		t.Errorf("EnvVar() = %q, want VOIDKIT_SERVER_HTTP_PORT", got)
+	}
+}
+
+func TestLoadExpandsEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", `
+server:
+  http:
+    port: ${TEST_HTTP_PORT}
+  grpc:
+    port: ${TEST_GRPC_PORT:-7070}
+cache:
+  type: memory
+  key_prefix: ${TEST_DOTENV_PREFIX}
+`)
+	t.Setenv("TEST_HTTP_PORT", "9090")
+	// The .env file sets unset variables only, t.Setenv restores them
+	t.Setenv("TEST_DOTENV_PREFIX", "")
+	os.Unsetenv("TEST_DOTENV_PREFIX")
+	t.Setenv("TEST_GRPC_PORT", "7171")
+	writeFile(t, dir, ".env", "TEST_DOTENV_PREFIX=dotenv:\nTEST_GRPC_PORT=1\n")
+
+	cfg, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{path}, Env: "test"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.HTTP.Port != 9090 {
+		t.Errorf("server.http.port = %d, want 9090", cfg.Server.HTTP.Port)
+	}
+	if cfg.Server.GRPC.Port != 7171 {
+		t.Errorf("server.grpc.port = %d, want 7171 from the environment", cfg.Server.GRPC.Port)
+	}
+	if cfg.Cache.KeyPrefix != "dotenv:" {
+		t.Errorf("cache.key_prefix = %q, want the .env value", cfg.Cache.KeyPrefix)
+	}
+}
+
+func TestLoadReportsUnresolvedVariables(t *testing.T) {
+	os.Unsetenv("TEST_UNSET_HOST")
+	os.Unsetenv("TEST_UNSET_PORT")
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+cache:
+  host: ${TEST_UNSET_HOST}
+  port: ${TEST_UNSET_PORT}
+database:
+  host: ${TEST_UNSET_HOST}
+`)
+
+	// A placeholder overridden by a later source needs no value
+	_, err := Load(context.Background(), testLogger(), LoadOptions{
+		Files: []string{path},
+		Env:   "test",
+		Set:   []string{"database.host=localhost"},
+	})
+	var unresolved *UnresolvedError
+	if !errors.As(err, &unresolved) {
+		t.Fatalf("Load() error = %v, want an UnresolvedError", err)
+	}
+	want := map[string][]string{
+		"cache.host": {"TEST_UNSET_HOST"},
+		"cache.port": {"TEST_UNSET_PORT"},
+	}
+	if !reflect.DeepEqual(unresolved.Placeholders, want) {
+		t.Errorf("Placeholders = %v, want %v", unresolved.Placeholders, want)
+	}
+	if got := err.Error(); got != "unresolved environment variables: cache.host (TEST_UNSET_HOST); cache.port (TEST_UNSET_PORT)" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestLoadEnvironmentOverrides(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+server:
+  http:
+    port: 8085
+features:
+  old_flow: true
+cache:
+  type: memory
+`)
+	t.Setenv("VOIDKIT_SERVER_HTTP_PORT", "9191")
+	t.Setenv("VOIDKIT_FEATURES", "{new_checkout: true}")
+
+	cfg, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{path}, Env: "test"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.HTTP.Port != 9191 {
+		t.Errorf("server.http.port = %d, want 9191", cfg.Server.HTTP.Port)
+	}
+	want := map[string]bool{"old_flow": true, "new_checkout": true}
+	if !reflect.DeepEqual(cfg.Features, want) {
+		t.Errorf("features = %v, want %v", cfg.Features, want)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("VOIDKIT_SERVER_GRPC_PORT", "9191")
+	t.Setenv("VOIDKIT_SERVER_GRPC_TLS_ENABLED", "true")
+	t.Setenv("VOIDKIT_DATABASE_PASSWORD", "vault://secret/data/db#password")
+
+	cfg, err := LoadEnv(LoadOptions{Files: []string{"missing.yaml"}, Env: "test", Set: []string{"server.listen.mode=single", "server.listen.port=9000"}})
+	if err != nil {
+		t.Fatalf("LoadEnv() error = %v", err)
+	}
+	if cfg.Server.GRPC.Port != 9191 || !cfg.Server.GRPC.TLS.Enabled {
+		t.Errorf("server.grpc = %+v, want the environment values", cfg.Server.GRPC)
+	}
+	if !cfg.Server.Listen.Mode.IsSingle() || cfg.Server.Listen.Port != 9000 {
+		t.Errorf("server.listen = %+v, want the --set values", cfg.Server.Listen)
+	}
+	if cfg.Server.HTTP.Port != 8085 || cfg.Server.Env != "test" {
+		t.Errorf("server.http.port = %d, server.environment = %q, want the defaults", cfg.Server.HTTP.Port, cfg.Server.Env)
+	}
+	// Files are not read and secrets not resolved
+	if cfg.Database.Password != "vault://secret/data/db#password" {
+		t.Errorf("database.password = %q, want the unresolved reference", cfg.Database.Password)
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KVStore is a key-value store holding config keys as paths below a prefix,
+// such as voidkitgo/server/http/port
+type KVStore interface {
+	// List returns every entry below the prefix, keyed by its path relative
+	// to the prefix
+	List(ctx context.Context) (map[string]string, error)
+}
+
+// NewKVStore creates the store of a URL: consul://host:8500/prefix reads the
+// Consul KV API, and file:///path/kv.yaml reads a YAML file mapping the same
+// paths to values, a local stand-in for the remote store
+func NewKVStore(rawURL string) (KVStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote config URL: %w", err)
+	}
+	switch u.Scheme {
+	case "consul":
+		scheme := u.Query().Get("scheme")
+		if scheme == "" {
+			scheme = "http"
+		}
+		return NewConsulStore(ConsulStoreParams{
+			Address: scheme + "://" + u.Host,
+			Prefix:  strings.Trim(u.Path, "/"),
+		}), nil
+	case "file":
+		return FileKVStore{Path: u.Host + u.Path}, nil
+	}
+	return nil, fmt.Errorf("unsupported remote config scheme %q, expected consul or file", u.Scheme)
+}
+
+// KVSource reads the entries of a KVStore, their values are parsed as YAML
+// so numbers, booleans and lists keep their type
+type KVSource struct {
+	// URL names the source
+	URL   string
+	Store KVStore
+}
+
+// Name returns the URL of the store
+func (s KVSource) Name() string {
+	return s.URL
+}
+
+// Load lists the entries of the store
+func (s KVSource) Load(ctx context.Context) (map[string]any, error) {
+	entries, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	for path, value := range entries {
+		path = strings.Trim(path, "/")
+		if path == "" {
+			continue
+		}
+		setKey(values, strings.ToLower(strings.ReplaceAll(path, "/", ".")), parseValue(value))
+	}
+	return values, nil
+}
+
+// FileKVStore reads the entries of a YAML file, such as
+//
+//	server/http/port: 9090
+//	features/new_checkout: true
+type FileKVStore struct {
+	Path string
+}
+
+// List reads the file
+func (s FileKVStore) List(ctx context.Context) (map[string]string, error) {
+	content, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key-value file: %w", err)
+	}
+	raw := map[string]any{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse key-value file %s: %w", s.Path, err)
+	}
+
+	entries := make(map[string]string, len(raw))
+	for path, value := range raw {
+		switch value := value.(type) {
+		case string:
+			entries[path] = value
+		default:
+			// Lists and maps are kept as YAML, as they would be stored remotely
+			encoded, err := yaml.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s: %w", path, err)
+			}
+			entries[path] = strings.TrimSpace(string(encoded))
+		}
+	}
+	return entries, nil
+}
+
+// ConsulStore reads the entries below a prefix of the Consul KV store
+type ConsulStore struct {
+	address string
+	prefix  string
+	token   string
+	client  *http.Client
+}
+
+type ConsulStoreParams struct {
+	// Address is the Consul HTTP API URL, like http://localhost:8500
+	Address string
+	// Prefix is the path holding the config keys, like voidkitgo
+	Prefix string
+	// Token authenticates the requests, CONSUL_HTTP_TOKEN by default
+	Token string
+	// Timeout bounds each request, 10 seconds by default
+	Timeout time.Duration
+}
+
+// NewConsulStore creates a Consul store
+func NewConsulStore(params ConsulStoreParams) *ConsulStore {
+	timeout := params.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &ConsulStore{
+		address: strings.TrimRight(params.Address, "/"),
+		prefix:  strings.Trim(params.Prefix, "/"),
+		token:   firstNonEmpty(params.Token, os.Getenv("CONSUL_HTTP_TOKEN")),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// List reads every entry below the prefix, none when the prefix does not exist
+func (s *ConsulStore) List(ctx context.Context) (map[string]string, error) {
+	// The trailing slash keeps prefixes such as app from matching app2
+	prefix := s.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.address+"/v1/kv/"+prefix+"?recurse=true", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", s.prefix, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", s.prefix, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list %s: consul responded %s", s.prefix, resp.Status)
+	}
+
+	var pairs []struct {
+		Key   string  `json:"Key"`
+		Value *string `json:"Value"`
+	}
+	if err := json.Unmarshal(body, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", s.prefix, err)
+	}
+
+	entries := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		// Folders have no value
+		if pair.Value == nil {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(*pair.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value of %s: %w", pair.Key, err)
+		}
+		entries[strings.TrimPrefix(pair.Key, prefix)] = string(value)
+	}
+	return entries, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Redacted returns the configuration as nested maps keyed like the config
+// file. Secrets are replaced when set, so the result is safe to print or log.
+func (c *Config) Redacted() map[string]any {
+	return plainValue(reflect.ValueOf(*c), true).(map[string]any)
+}
+
+// plainValue converts v into plain maps, slices and scalars, redacting
+// secrets when redact is set
+func plainValue(v reflect.Value, redact bool) any {
+	switch v.Type() {
+	case reflect.TypeOf(time.Duration(0)):
+		return time.Duration(v.Int()).String()
+	case reflect.TypeOf(Secret("")):
+		if redact {
+			return Secret(v.String()).String()
+		}
+		return v.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			out[name] = plainValue(v.Field(i), redact)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = plainValue(v.Index(i), redact)
+		}
+		return out
+	case reflect.Map:
+		out := map[string]any{}
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = plainValue(iter.Value(), redact)
+		}
+		return out
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return plainValue(v.Elem(), redact)
+	case reflect.String:
+		return v.String()
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationPattern matches the durations accepted by time.ParseDuration
+const durationPattern = `^-?(0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+
+// JSONSchema returns a JSON Schema of the config file, used by editors to
+// validate and complete it. It is derived from the mapstructure, default and
+// validate tags of Config, unknown keys are rejected as LoadConfig does.
+func JSONSchema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "voidkitgo configuration"
+	return schema
+}
+
+// typeSchema returns the schema of values of type t
+func typeSchema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			properties[name] = fieldSchema(field)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	}
+	return map[string]any{}
+}
+
+// fieldSchema returns the schema of a struct field, adding its default and
+// the constraints of its validate tag that JSON Schema can express
+func fieldSchema(field reflect.StructField) map[string]any {
+	schema := typeSchema(field.Type)
+
+	if value, ok := defaultValue(field); ok {
+		schema["default"] = value
+	}
+
+	// Rules after dive apply to the items of a slice
+	target := schema
+	omitEmpty := false
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			if items, ok := schema["items"].(map[string]any); ok {
+				target = items
+			}
+		case "omitempty":
+			omitEmpty = true
+		case "oneof":
+			var values []any
+			if omitEmpty && target["type"] == "string" {
+				values = append(values, "")
+			}
+			for _, value := range strings.Fields(param) {
+				values = append(values, schemaValue(field.Type, value))
+			}
+			target["enum"] = values
+		case "gte":
+			if isNumber(target) {
+				target["minimum"] = schemaValue(field.Type, param)
+			}
+		case "gt":
+			if isNumber(target) {
+				target["exclusiveMinimum"] = schemaValue(field.Type, param)
+			}
+		case "lte":
+			if isNumber(target) {
+				target["maximum"] = schemaValue(field.Type, param)
+			}
+		case "startswith":
+			target["pattern"] = "^" + regexp.QuoteMeta(param)
+		}
+	}
+	return schema
+}
+
+func isNumber(schema map[string]any) bool {
+	return schema["type"] == "integer" || schema["type"] == "number"
+}
+
+// defaultValue returns the value of the default tag of a field, slices take
+// comma separated values
+func defaultValue(field reflect.StructField) (any, bool) {
+	value, ok := field.Tag.Lookup("default")
+	if !ok {
+		return nil, false
+	}
+	if field.Type.Kind() != reflect.Slice {
+		return schemaValue(field.Type, value), true
+	}
+	var items []any
+	for _, item := range strings.Split(value, ",") {
+		items = append(items, schemaValue(field.Type.Elem(), item))
+	}
+	return items, true
+}
+
+// schemaValue converts a tag value into the JSON type of t
+func schemaValue(t reflect.Type, value string) any {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return value
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
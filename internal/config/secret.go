@@ -0,0 +1,313 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedValue replaces the value of secrets that are set
+const redactedValue = "[REDACTED]"
+
+// Secret is a sensitive configuration value. It may be given literally or as
+// a reference such as file:///run/secrets/db_password, env://DB_PASSWORD or
+// vault://secret/data/db#password, which LoadConfig resolves through the
+// SecretProvider registered for the scheme. Values whose scheme has no
+// provider, such as a password containing "://", are taken literally.
+// Formatting and encoding a Secret yields a redacted placeholder, Value
+// returns the plaintext.
+type Secret string
+
+// Value returns the plaintext secret
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String returns a redacted placeholder, so secrets are not leaked by logs
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// GoString redacts the secret in %#v formatting
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+// MarshalJSON encodes the redacted placeholder
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalYAML encodes the redacted placeholder
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+// SecretProvider resolves secret references of a URL scheme
+type SecretProvider interface {
+	// Resolve returns the secret referenced by ref
+	Resolve(ctx context.Context, ref *url.URL) (string, error)
+}
+
+// SecretProviderFunc adapts a function to the SecretProvider interface
+type SecretProviderFunc func(ctx context.Context, ref *url.URL) (string, error)
+
+// Resolve calls f
+func (f SecretProviderFunc) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"file":  SecretProviderFunc(resolveFileSecret),
+		"env":   SecretProviderFunc(resolveEnvSecret),
+		"vault": NewVaultProvider(VaultProviderParams{}),
+	}
+)
+
+// RegisterSecretProvider registers the provider resolving references of
+// scheme, replacing any previous one. It must be called before LoadConfig.
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[scheme] = provider
+}
+
+// secretProvider returns the provider of scheme, nil if none is registered
+func secretProvider(scheme string) SecretProvider {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	return secretProviders[scheme]
+}
+
+// secretReference returns the scheme and provider of value when it is a
+// reference to a registered secret provider
+func secretReference(value string) (string, SecretProvider, bool) {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return "", nil, false
+	}
+	provider := secretProvider(scheme)
+	return scheme, provider, provider != nil
+}
+
+// resolveSecrets replaces every Secret of cfg holding a reference to a
+// registered provider with the value it refers to, and returns the errors of
+// all failed references
+func resolveSecrets(ctx context.Context, cfg *Config) error {
+	var errs []error
+	walkSecrets(reflect.ValueOf(cfg).Elem(), "", func(key string, secret reflect.Value) {
+		value := secret.String()
+		scheme, provider, ok := secretReference(value)
+		if !ok {
+			return
+		}
+		ref, err := url.Parse(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid secret reference: %w", key, err))
+			return
+		}
+		resolved, err := provider.Resolve(ctx, ref)
+		if err != nil {
+			// The reference is reported without its fragment or credentials
+			errs = append(errs, fmt.Errorf("%s: failed to resolve %s secret: %w", key, scheme, err))
+			return
+		}
+		secret.SetString(resolved)
+	})
+	return errors.Join(errs...)
+}
+
+// walkSecrets calls fn with every settable Secret reachable from v, key being
+// its config key
+func walkSecrets(v reflect.Value, key string, fn func(key string, secret reflect.Value)) {
+	if v.Type() == reflect.TypeOf(Secret("")) {
+		if v.CanSet() {
+			fn(key, v)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			walkSecrets(v.Field(i), joinKey(key, name), fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkSecrets(v.Index(i), fmt.Sprintf("%s[%d]", key, i), fn)
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			walkSecrets(v.Elem(), key, fn)
+		}
+	}
+}
+
+// resolveFileSecret reads file:///path, without the trailing newline editors
+// and orchestrators tend to add
+func resolveFileSecret(ctx context.Context, ref *url.URL) (string, error) {
+	path := ref.Path
+	if ref.Host != "" {
+		// file://relative/path keeps the first segment in the host
+		path = ref.Host + ref.Path
+	}
+	if path == "" {
+		return "", errors.New("missing file path")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// resolveEnvSecret reads the variable of env://NAME
+func resolveEnvSecret(ctx context.Context, ref *url.URL) (string, error) {
+	name := ref.Host + ref.Path
+	if name == "" {
+		return "", errors.New("missing variable name")
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// VaultProvider resolves vault://path#key references by reading path through
+// the Vault HTTP API and returning its key field. Both KV version 1 and the
+// data envelope of KV version 2 are supported.
+type VaultProvider struct {
+	address   string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+type VaultProviderParams struct {
+	// Address is the Vault server URL, VAULT_ADDR by default
+	Address string
+	// Token authenticates the requests, VAULT_TOKEN by default
+	Token string
+	// Namespace is sent as X-Vault-Namespace when set, VAULT_NAMESPACE by default
+	Namespace string
+	// Timeout bounds each request, 10 seconds by default
+	Timeout time.Duration
+}
+
+// NewVaultProvider creates a Vault provider, unset parameters are read from
+// the standard Vault environment variables when a secret is resolved
+func NewVaultProvider(params VaultProviderParams) *VaultProvider {
+	timeout := params.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &VaultProvider{
+		address:   params.Address,
+		token:     params.Token,
+		namespace: params.Namespace,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Resolve reads the secret referenced by vault://path#key
+func (p *VaultProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	address := firstNonEmpty(p.address, os.Getenv("VAULT_ADDR"))
+	token := firstNonEmpty(p.token, os.Getenv("VAULT_TOKEN"))
+	namespace := firstNonEmpty(p.namespace, os.Getenv("VAULT_NAMESPACE"))
+	if address == "" {
+		return "", errors.New("vault address is not configured, set VAULT_ADDR")
+	}
+
+	path := strings.Trim(ref.Host+ref.Path, "/")
+	key := ref.Fragment
+	if path == "" || key == "" {
+		return "", errors.New("expected a reference like vault://secret/data/name#key")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(address, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read %s: vault responded %s", path, resp.Status)
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	// KV version 2 nests the fields in another data object
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nested
+		}
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in %s", key, path)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// redactSecret returns value when it is a reference to a registered secret
+// provider, and a redacted placeholder otherwise
+func redactSecret(value string) string {
+	if _, _, ok := secretReference(value); ok {
+		return value
+	}
+	return Secret(value).String()
+}
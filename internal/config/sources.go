@@ -0,0 +1,317 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigDir is the directory next to the first config file whose
+// files are merged after it
+const DefaultConfigDir = "conf.d"
+
+// Source supplies configuration values. Sources are merged in order, values
+// of later sources taking precedence over those of earlier ones.
+type Source interface {
+	// Name identifies the source in errors and in config explain
+	Name() string
+	// Load returns the values of the source as nested maps keyed like the
+	// config file
+	Load(ctx context.Context) (map[string]any, error)
+}
+
+// LoadOptions selects the sources of the configuration, which are merged in
+// this order of increasing precedence:
+//
+//  1. the defaults declared by the default tags of Config, and the profiles
+//     of the built-in environments
+//  2. each of Files, followed by its environment overlay such as default.production.yaml
+//  3. the files of Dir, in lexical order
+//  4. the Remote key-value store
+//  5. the VOIDKIT_ environment variables
+//  6. the Set overrides
+type LoadOptions struct {
+	// Files are YAML, JSON or TOML config files. The directory of the first
+	// one holds the .env file and the default Dir.
+	Files []string
+	// Env selects the environment overlays, which are optional, and is the
+	// default server.environment
+	Env string
+	// Dir holds more config files, conf.d next to the first file by default.
+	// A missing directory is ignored.
+	Dir string
+	// Remote is the URL of a key-value store, see NewKVStore
+	Remote string
+	// Set holds key=value overrides such as server.http.port=9090, values
+	// are parsed as YAML
+	Set []string
+}
+
+// sources returns the sources of the options in precedence order
+func (o LoadOptions) sources() ([]Source, error) {
+	if len(o.Files) == 0 {
+		return nil, errors.New("no config file given")
+	}
+
+	sources := []Source{defaultsSource{env: o.Env}}
+	for _, file := range o.Files {
+		sources = append(sources, FileSource{Path: file})
+		if overlay := overlayPath(file, o.Env); fileExists(overlay) {
+			sources = append(sources, FileSource{Path: overlay})
+		}
+	}
+
+	files, err := dirFiles(o.dir())
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		sources = append(sources, FileSource{Path: file})
+	}
+
+	if o.Remote != "" {
+		store, err := NewKVStore(o.Remote)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, KVSource{URL: o.Remote, Store: store})
+	}
+
+	sources = append(sources, envSource{})
+	if len(o.Set) > 0 {
+		sources = append(sources, setSource(o.Set))
+	}
+	return sources, nil
+}
+
+// dir returns the config directory of the options
+func (o LoadOptions) dir() string {
+	if o.Dir != "" || len(o.Files) == 0 {
+		return o.Dir
+	}
+	return filepath.Join(filepath.Dir(o.Files[0]), DefaultConfigDir)
+}
+
+// overlayPath returns the environment overlay of a config file, named like
+// default.production.yaml
+func overlayPath(file, env string) string {
+	ext := filepath.Ext(file)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(file, ext), env, ext)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// isConfigFile reports whether the extension of path is a supported format
+func isConfigFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json", ".toml":
+		return true
+	}
+	return false
+}
+
+// dirFiles returns the config files of dir in lexical order, none when the
+// directory does not exist
+func dirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isConfigFile(entry.Name()) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// FileSource reads a YAML, JSON or TOML file, the format being given by its
+// extension
+type FileSource struct {
+	Path string
+}
+
+// Name returns the path of the file
+func (s FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file
+func (s FileSource) Load(ctx context.Context) (map[string]any, error) {
+	content, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(s.Path)) {
+	case ".json":
+		err = json.Unmarshal(content, &values)
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		err = yaml.Unmarshal(content, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", s.Path, err)
+	}
+	return values, nil
+}
+
+// defaultsSource holds the defaults declared by the default tags of Config,
+// the profiles of the built-in environments and the environment selected by
+// --env
+type defaultsSource struct {
+	env string
+}
+
+func (defaultsSource) Name() string {
+	return "defaults"
+}
+
+func (s defaultsSource) Load(ctx context.Context) (map[string]any, error) {
+	values := map[string]any{}
+	walkKeys(reflect.TypeOf(Config{}), "", func(key string, field reflect.StructField) {
+		if value, ok := defaultValue(field); ok {
+			setKey(values, key, value)
+		}
+	})
+	if s.env != "" {
+		setKey(values, "server.environment", strings.ToLower(s.env))
+	}
+	for env, profile := range defaultProfiles() {
+		setKey(values, "environments."+string(env), plainValue(reflect.ValueOf(profile), false))
+	}
+	return values, nil
+}
+
+// envSource reads the VOIDKIT_ variable of every config key. Maps and lists
+// of objects are given as YAML, like VOIDKIT_FEATURES='{new_checkout: true}'.
+type envSource struct{}
+
+func (envSource) Name() string {
+	return "environment"
+}
+
+func (envSource) Load(ctx context.Context) (map[string]any, error) {
+	values := map[string]any{}
+	walkKeys(reflect.TypeOf(Config{}), "", func(key string, field reflect.StructField) {
+		value := os.Getenv(EnvVar(key))
+		if value == "" {
+			return
+		}
+		kind := field.Type.Kind()
+		if kind == reflect.Map || kind == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			setKey(values, key, parseValue(value))
+			return
+		}
+		setKey(values, key, value)
+	})
+	return values, nil
+}
+
+// setSource holds key=value overrides given on the command line
+type setSource []string
+
+func (setSource) Name() string {
+	return "--set"
+}
+
+func (s setSource) Load(ctx context.Context) (map[string]any, error) {
+	values := map[string]any{}
+	for _, override := range s {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid override %q, expected key=value", override)
+		}
+		setKey(values, strings.ToLower(key), parseValue(value))
+	}
+	return values, nil
+}
+
+// parseValue parses a YAML value given as text, so numbers, booleans and
+// lists keep their type. Invalid YAML is kept as a string.
+func parseValue(text string) any {
+	var value any
+	if err := yaml.Unmarshal([]byte(text), &value); err != nil || value == nil {
+		return text
+	}
+	return value
+}
+
+// walkKeys calls fn with the key of every field of t that is not a nested struct
+func walkKeys(t reflect.Type, prefix string, fn func(key string, field reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		key := joinKey(prefix, name)
+		if field.Type.Kind() == reflect.Struct {
+			walkKeys(field.Type, key, fn)
+			continue
+		}
+		fn(key, field)
+	}
+}
+
+// setKey sets the value of a dotted key in nested maps
+func setKey(values map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := values[part].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			values[part] = next
+		}
+		values = next
+	}
+	values[parts[len(parts)-1]] = value
+}
+
+// mergeValues merges src into dst, nested maps are merged and any other
+// value replaces the one of dst
+func mergeValues(dst, src map[string]any) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]any); ok {
+			if dstMap, ok := dst[key].(map[string]any); ok {
+				mergeValues(dstMap, srcMap)
+				continue
+			}
+			copied := map[string]any{}
+			mergeValues(copied, srcMap)
+			dst[key] = copied
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// flattenValues calls fn with the key and value of every leaf of values,
+// lists being leaves
+func flattenValues(prefix string, values map[string]any, fn func(key string, value any)) {
+	for k, value := range values {
+		key := joinKey(prefix, k)
+		if nested, ok := value.(map[string]any); ok {
+			flattenValues(key, nested, fn)
+			continue
+		}
+		fn(key, value)
+	}
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadMergeOrder(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", `
+cache:
+  type: memory
+  key_prefix: base
+server:
+  http:
+    port: 1001
+  grpc:
+    port: 1002
+logging:
+  level: warn
+`)
+	overlay := writeFile(t, dir, "base.test.yaml", "cache:\n  key_prefix: overlay\nserver:\n  http:\n    port: 2001\n")
+	confA := writeFile(t, dir, "conf.d/10-a.yaml", "cache:\n  key_prefix: conf-a\n")
+	confB := writeFile(t, dir, "conf.d/20-b.json", `{"cache": {"key_prefix": "conf-b"}, "server": {"grpc": {"port": 3002}}}`)
+	writeFile(t, dir, "conf.d/README.md", "not a config file")
+	kv := writeFile(t, dir, "kv.yaml", "cache/key_prefix: remote\nserver/admin/port: 4001\n")
+	remote := "file://" + kv
+	t.Setenv("VOIDKIT_CACHE_KEY_PREFIX", "env")
+
+	opts := LoadOptions{
+		Files:  []string{base},
+		Env:    "test",
+		Remote: remote,
+		Set:    []string{"cache.key_prefix=set"},
+	}
+	cfg, err := Load(context.Background(), testLogger(), opts)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Each key takes the value of the last source setting it
+	if cfg.Cache.KeyPrefix != "set" {
+		t.Errorf("cache.key_prefix = %q, want the --set value", cfg.Cache.KeyPrefix)
+	}
+	if cfg.Server.HTTP.Port != 2001 {
+		t.Errorf("server.http.port = %d, want 2001 from the overlay", cfg.Server.HTTP.Port)
+	}
+	if cfg.Server.GRPC.Port != 3002 {
+		t.Errorf("server.grpc.port = %d, want 3002 from conf.d", cfg.Server.GRPC.Port)
+	}
+	if cfg.Server.Admin.Port != 4001 {
+		t.Errorf("server.admin.port = %d, want 4001 from the remote store", cfg.Server.Admin.Port)
+	}
+	if cfg.Logging.Level != "warn" {
+		t.Errorf("logging.level = %q, want warn from the base file", cfg.Logging.Level)
+	}
+
+	// Provenance lists the sources of a key in precedence order
+	origins, err := Explain(context.Background(), testLogger(), opts, "cache.key_prefix")
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	want := []Origin{
+		{Key: "cache.key_prefix", Source: "defaults", Value: "voidkitgo:cache:"},
+		{Key: "cache.key_prefix", Source: base, Value: "base"},
+		{Key: "cache.key_prefix", Source: overlay, Value: "overlay"},
+		{Key: "cache.key_prefix", Source: confA, Value: "conf-a"},
+		{Key: "cache.key_prefix", Source: confB, Value: "conf-b"},
+		{Key: "cache.key_prefix", Source: remote, Value: "remote"},
+		{Key: "cache.key_prefix", Source: "environment", Value: "env"},
+		{Key: "cache.key_prefix", Source: "--set", Value: "set"},
+	}
+	if !reflect.DeepEqual(origins, want) {
+		t.Errorf("Explain() =\n%v\nwant\n%v", origins, want)
+	}
+}
+
+func TestLoadMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFile(t, dir, "first.yaml", "server:\n  http:\n    port: 1001\n  grpc:\n    port: 1002\ncache:\n  type: memory\n")
+	writeFile(t, dir, "first.staging.yaml", "server:\n  grpc:\n    port: 1102\n")
+	second := writeFile(t, filepath.Join(dir, "other"), "second.toml", "[server.http]\nport = 2001\n")
+
+	cfg, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{second, first}, Env: "staging"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// Later files and their overlays take precedence over earlier ones
+	if cfg.Server.HTTP.Port != 1001 || cfg.Server.GRPC.Port != 1102 {
+		t.Errorf("ports = %d, %d, want 1001, 1102", cfg.Server.HTTP.Port, cfg.Server.GRPC.Port)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+database:
+  password: hunter2
+cache:
+  password: env://CACHE_PASSWORD
+server:
+  http:
+    port: 9090
+`)
+	opts := LoadOptions{Files: []string{path}, Env: "test", Set: []string{"server.http.port=9191"}}
+
+	origins, err := Explain(context.Background(), testLogger(), opts, "")
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	values := map[string][]any{}
+	for _, origin := range origins {
+		values[origin.Key] = append(values[origin.Key], origin.Value)
+	}
+	// Literal secrets are redacted, references are shown
+	if got := values["database.password"]; !reflect.DeepEqual(got, []any{redactedValue}) {
+		t.Errorf("database.password origins = %v, want it redacted", got)
+	}
+	if got := values["cache.password"]; !reflect.DeepEqual(got, []any{"env://CACHE_PASSWORD"}) {
+		t.Errorf("cache.password origins = %v, want the reference", got)
+	}
+
+	// Keys below the given key are explained, sorted by key
+	origins, err = Explain(context.Background(), testLogger(), opts, "Server.HTTP")
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	var keys []string
+	var port []Origin
+	for _, origin := range origins {
+		if !strings.HasPrefix(origin.Key, "server.http.") {
+			t.Errorf("Explain(server.http) returned %s", origin.Key)
+		}
+		keys = append(keys, origin.Key)
+		if origin.Key == "server.http.port" {
+			port = append(port, origin)
+		}
+	}
+	if !slices.IsSorted(keys) {
+		t.Errorf("Explain() keys %v are not sorted", keys)
+	}
+	// Values keep the type they were parsed with, which varies by source
+	wantPort := []string{"defaults=8085", path + "=9090", "--set=9191"}
+	var gotPort []string
+	for _, origin := range port {
+		gotPort = append(gotPort, fmt.Sprintf("%s=%v", origin.Source, origin.Value))
+	}
+	if !reflect.DeepEqual(gotPort, wantPort) {
+		t.Errorf("server.http.port origins = %v, want %v", gotPort, wantPort)
+	}
+}
+
+func TestSetSourceRejectsInvalidOverrides(t *testing.T) {
+	for _, override := range []string{"server.http.port", "=9090"} {
+		if _, err := setSource([]string{override}).Load(context.Background()); err == nil {
+			t.Errorf("Load(%q) error = nil, want an invalid override error", override)
+		}
+	}
+
+	values, err := setSource([]string{"Server.HTTP.Port=9090", "auth.algorithms=[HS256, RS256]", "cache.key_prefix=a=b"}).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]any{
+		"server": map[string]any{"http": map[string]any{"port": 9090}},
+		"auth":   map[string]any{"algorithms": []any{"HS256", "RS256"}},
+		"cache":  map[string]any{"key_prefix": "a=b"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Load() = %v, want %v", values, want)
+	}
+}
+
+func TestLoadEnvironmentOverlay(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "default.yaml", `
+cache:
+  type: redis
+  host: localhost
+  port: "6379"
+  key_prefix: base
+server:
+  http:
+    port: 1001
+environments:
+  qa:
+    reflection: true
+`)
+	writeFile(t, dir, "default.development.yaml", "cache:\n  type: memory\n")
+	writeFile(t, dir, "default.qa.yaml", "server:\n  http:\n    port: 1101\n")
+
+	tests := []struct {
+		env      string
+		wantType string
+		wantPort int
+	}{
+		{env: "development", wantType: "memory", wantPort: 1001},
+		{env: "qa", wantType: "redis", wantPort: 1101},
+		// Environments without an overlay use default.yaml alone
+		{env: "production", wantType: "redis", wantPort: 1001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			cfg, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{base}, Env: tt.env})
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			// The overlay layers over the keys it sets only
+			if cfg.Cache.Type != tt.wantType || cfg.Server.HTTP.Port != tt.wantPort || cfg.Cache.KeyPrefix != "base" {
+				t.Errorf("cache.type = %q, server.http.port = %d, cache.key_prefix = %q, want %q, %d, base",
+					cfg.Cache.Type, cfg.Server.HTTP.Port, cfg.Cache.KeyPrefix, tt.wantType, tt.wantPort)
+			}
+			if string(cfg.Server.Env) != tt.env {
+				t.Errorf("server.environment = %q, want %q", cfg.Server.Env, tt.env)
+			}
+		})
+	}
+}
+
+func TestLoadUnknownEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "default.yaml", "cache:\n  type: memory\n")
+	writeFile(t, dir, "default.prod.yaml", "cache:\n  key_prefix: prod\n")
+
+	_, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{base}, Env: "prod"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	want := []FieldError{{
+		Key:     "server.environment",
+		Message: `must be one of the environments declared under environments (development, production, staging, test), got "prod"`,
+	}}
+	if !reflect.DeepEqual(validationErr.Errors, want) {
+		t.Errorf("Errors = %v, want %v", validationErr.Errors, want)
+	}
+}
+
+func TestLoadShippedProfiles(t *testing.T) {
+	tests := []struct {
+		env      string
+		wantType string
+	}{
+		{env: "development", wantType: "memory"},
+		{env: "production", wantType: "redis"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			cfg, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{"../../default.yaml"}, Env: tt.env})
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Cache.Type != tt.wantType {
+				t.Errorf("cache.type = %q, want %q", cfg.Cache.Type, tt.wantType)
+			}
+			// Migrations change a database that may be shared, they only run on request
+			if cfg.Database.Migrations.OnStart {
+				t.Errorf("database.migrations.on_start = true, want false")
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is an invalid or unknown config key
+type FieldError struct {
+	// Key is the path of the key in the config file, like server.http.port
+	Key     string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+// ValidationError lists every invalid and unknown key of a configuration
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		parts[i] = fieldErr.Error()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// validateConfig validates the configuration using struct tags and returns
+// an error for every invalid field
+func validateConfig(cfg *Config) []FieldError {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	validate.RegisterStructValidation(validateBurst, RateLimitConfig{}, RateLimitOverride{})
+
+	err := validate.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []FieldError{{Message: err.Error()}}
+	}
+
+	fieldErrors := make([]FieldError, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		// Namespaces start with the name of the validated type
+		_, key, _ := strings.Cut(e.Namespace(), ".")
+		fieldErrors = append(fieldErrors, FieldError{Key: key, Message: validationMessage(e)})
+	}
+	return fieldErrors
+}
+
+// validateEnvironment checks that server.environment and the environment
+// selecting the overlays are declared under environments
+func validateEnvironment(cfg *Config, env string) []FieldError {
+	names := make([]string, 0, len(cfg.Environments))
+	for name := range cfg.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	unknown := func(name string) string {
+		return fmt.Sprintf("must be one of the environments declared under environments (%s), got %q", strings.Join(names, ", "), name)
+	}
+
+	var fieldErrors []FieldError
+	if _, ok := cfg.Environments[string(cfg.Server.Env)]; !ok {
+		fieldErrors = append(fieldErrors, FieldError{Key: "server.environment", Message: unknown(string(cfg.Server.Env))})
+	}
+	if env = strings.ToLower(env); env != "" && env != string(cfg.Server.Env) {
+		if _, ok := cfg.Environments[env]; !ok {
+			fieldErrors = append(fieldErrors, FieldError{Key: "--env", Message: unknown(env)})
+		}
+	}
+	return fieldErrors
+}
+
+// validateBurst requires a burst of at least one request when the rate is
+// positive, a bucket without capacity rejecting every request
+func validateBurst(sl validator.StructLevel) {
+	var rate float64
+	var burst int
+	switch limit := sl.Current().Interface().(type) {
+	case RateLimitConfig:
+		rate, burst = limit.Rate, limit.Burst
+	case RateLimitOverride:
+		rate, burst = limit.Rate, limit.Burst
+	}
+	if rate > 0 && burst < 1 {
+		sl.ReportError(burst, "burst", "Burst", "burst_with_rate", "")
+	}
+}
+
+// validationMessage describes the failed rule of e
+func validationMessage(e validator.FieldError) string {
+	param := e.Param()
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "required_if":
+		fields := strings.Fields(param)
+		conditions := make([]string, 0, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			conditions = append(conditions, fmt.Sprintf("%s is %s", snakeCase(fields[i]), fields[i+1]))
+		}
+		return "is required when " + strings.Join(conditions, " and ")
+	case "required_without":
+		return fmt.Sprintf("is required when %s is not set", snakeCase(param))
+	case "excluded_with":
+		return fmt.Sprintf("must not be set along with %s", snakeCase(param))
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got %q", strings.ReplaceAll(param, " ", ", "), fmt.Sprint(e.Value()))
+	case "gte":
+		return "must be at least " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lte":
+		return "must be at most " + param
+	case "ltefield":
+		return "must not exceed " + snakeCase(param)
+	case "startswith":
+		return fmt.Sprintf("must start with %q", param)
+	case "url":
+		return "must be a valid URL"
+	case "burst_with_rate":
+		return "must be at least 1 when rate is greater than 0"
+	}
+	if param != "" {
+		return fmt.Sprintf("failed the %s=%s rule", e.Tag(), param)
+	}
+	return fmt.Sprintf("failed the %s rule", e.Tag())
+}
+
+// snakeCase converts a field name such as ClientAuth into its config key
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unknownKeys returns the keys of value, read from a config file, that match
+// no field of t. Values of the wrong type are left to unmarshalling.
+func unknownKeys(prefix string, value any, t reflect.Type) []string {
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		values, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if field.IsExported() && name != "" && name != "-" {
+				fields[name] = field.Type
+			}
+		}
+		for k, item := range values {
+			key := joinKey(prefix, strings.ToLower(k))
+			fieldType, ok := fields[strings.ToLower(k)]
+			if !ok {
+				unknown = append(unknown, key)
+				continue
+			}
+			unknown = append(unknown, unknownKeys(key, item, fieldType)...)
+		}
+	case reflect.Map:
+		values, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for k, item := range values {
+			unknown = append(unknown, unknownKeys(joinKey(prefix, strings.ToLower(k)), item, t.Elem())...)
+		}
+	case reflect.Slice:
+		items, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			unknown = append(unknown, unknownKeys(fmt.Sprintf("%s[%d]", prefix, i), item, t.Elem())...)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
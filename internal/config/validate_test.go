@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadReportsEveryFieldError(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+server:
+  htpp:
+    port: 8080
+  listen:
+    mode: single
+  shutdown:
+    drain_timeout: -1s
+cache:
+  type: memcached
+rate_limit:
+  rate: 10
+  burst: 0
+  overrides:
+    - route: "GET /v1/orders"
+      rate: 1
+      burst: 0
+      typo: true
+    - rate: 1
+      burst: 1
+environments:
+  development:
+    reflektion: true
+`)
+
+	_, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{path}, Env: "development"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+
+	// Unknown keys are reported first, by source, then invalid values
+	want := []FieldError{
+		{Key: "environments.development.reflektion", Message: "unknown key in " + path},
+		{Key: "rate_limit.overrides[0].typo", Message: "unknown key in " + path},
+		{Key: "server.htpp", Message: "unknown key in " + path},
+		{Key: "server.listen.port", Message: "is required when mode is single"},
+		{Key: "server.shutdown.drain_timeout", Message: "must be at least 0"},
+		{Key: "rate_limit.overrides[0].burst", Message: "must be at least 1 when rate is greater than 0"},
+		{Key: "rate_limit.overrides[1].method", Message: "is required when route is not set"},
+		{Key: "rate_limit.burst", Message: "must be at least 1 when rate is greater than 0"},
+		{Key: "cache.type", Message: `must be one of redis, memory, got "memcached"`},
+	}
+	if !reflect.DeepEqual(validationErr.Errors, want) {
+		t.Errorf("Errors =\n%v\nwant\n%v", validationErr.Errors, want)
+	}
+}
+
+func TestLoadAppliesTagDefaults(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.yaml", "cache:\n  host: localhost\n  port: \"6379\"\n")
+	cfg, err := Load(context.Background(), testLogger(), LoadOptions{Files: []string{path}, Env: "Development"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.HTTP.Port != 8085 || cfg.Server.GRPC.Port != 8086 {
+		t.Errorf("ports = %d, %d, want the defaults 8085, 8086", cfg.Server.HTTP.Port, cfg.Server.GRPC.Port)
+	}
+	if cfg.Cache.Type != "redis" || cfg.Cache.DefaultTTL != 5*time.Minute {
+		t.Errorf("cache = %q, %s, want redis, 5m", cfg.Cache.Type, cfg.Cache.DefaultTTL)
+	}
+	// The environment defaults to --env, lowercased, and selects a built-in profile
+	if cfg.Server.Env != Development {
+		t.Errorf("server.environment = %q, want development", cfg.Server.Env)
+	}
+	if profile := cfg.Profile(); !profile.Reflection || !reflect.DeepEqual(profile.CORS.AllowedOrigins, []string{"*"}) {
+		t.Errorf("Profile() = %+v, want the built-in development profile", profile)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	values := map[string]any{
+		"Server": map[string]any{
+			"HTTP":    map[string]any{"port": 1, "prot": 2},
+			"unknown": true,
+		},
+		"features": map[string]any{"any_flag": true},
+		// Values of the wrong type are left to unmarshalling
+		"cache": "redis",
+	}
+	want := []string{"server.http.prot", "server.unknown"}
+	if got := unknownKeys("", values, reflect.TypeOf(Config{})); !reflect.DeepEqual(got, want) {
+		t.Errorf("unknownKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+	properties := schema["properties"].(map[string]any)
+	server := properties["server"].(map[string]any)["properties"].(map[string]any)
+	http := server["http"].(map[string]any)["properties"].(map[string]any)
+	if got := fmt.Sprint(http["port"].(map[string]any)["default"]); got != "8085" {
+		t.Errorf("server.http.port default = %v, want 8085", got)
+	}
+	cache := properties["cache"].(map[string]any)["properties"].(map[string]any)
+	if got := cache["type"].(map[string]any)["enum"]; !reflect.DeepEqual(got, []any{"redis", "memory"}) {
+		t.Errorf("cache.type enum = %v, want [redis memory]", got)
+	}
+}
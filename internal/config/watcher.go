@@ -0,0 +1,297 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// reloadDebounce coalesces the burst of events produced by a single file update
+const reloadDebounce = 100 * time.Millisecond
+
+// kubernetesDataDir is the symlink Kubernetes swaps to update mounted ConfigMaps
+const kubernetesDataDir = "..data"
+
+// Event is published to subscribers when a reload changes hot keys, it is
+// one of LogLevelChanged, RateLimitChanged or FeaturesChanged
+type Event interface {
+	configEvent()
+}
+
+// LogLevelChanged is published when logging.level changes
+type LogLevelChanged struct {
+	Old, New string
+}
+
+// RateLimitChanged is published when the rates, bursts or overrides of
+// rate_limit change
+type RateLimitChanged struct {
+	Old, New RateLimitConfig
+}
+
+// FeaturesChanged is published when a feature flag is added, removed or toggled
+type FeaturesChanged struct {
+	Old, New map[string]bool
+}
+
+func (LogLevelChanged) configEvent()  {}
+func (RateLimitChanged) configEvent() {}
+func (FeaturesChanged) configEvent()  {}
+
+// RestartRequiredError is returned by Reload when keys that cannot change
+// while the server runs, such as ports, differ from the running configuration
+type RestartRequiredError struct {
+	Changes []Change
+}
+
+func (e *RestartRequiredError) Error() string {
+	keys := make([]string, len(e.Changes))
+	for i, change := range e.Changes {
+		keys[i] = change.Key
+	}
+	return "restart required to change " + strings.Join(keys, ", ")
+}
+
+// Watcher reloads the configuration when its files change or the process
+// receives SIGHUP, which also reloads the remote store. A reloaded
+// configuration is validated and only applied when every changed key is hot,
+// subscribers are then notified of the changes.
+type Watcher struct {
+	logger  *logrus.Logger
+	options LoadOptions
+
+	// mu serializes reloads and guards subscribers
+	mu          sync.Mutex
+	config      atomic.Pointer[Config]
+	subscribers []func(Event)
+
+	watcher *fsnotify.Watcher
+	ready   atomic.Bool
+}
+
+type WatcherParams struct {
+	Logger *logrus.Logger
+	// Options select the sources given to Load
+	Options LoadOptions
+	// Config is the configuration loaded on start
+	Config *Config
+}
+
+// NewWatcher creates a watcher of the config files, which starts watching
+// once registered with the server
+func NewWatcher(params WatcherParams) *Watcher {
+	w := &Watcher{
+		logger:  params.Logger,
+		options: params.Options,
+	}
+	w.config.Store(params.Config)
+	return w
+}
+
+// Config returns the latest applied configuration
+func (w *Watcher) Config() *Config {
+	return w.config.Load()
+}
+
+// Subscribe calls fn with the events of every applied reload, in the order
+// subscribers were added
+func (w *Watcher) Subscribe(fn func(Event)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Reload loads and validates the config files and applies them. The running
+// configuration is kept when loading fails or a key that is not hot changed,
+// in which case a *RestartRequiredError is returned.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next, err := Load(context.Background(), w.logger, w.options)
+	if err != nil {
+		return err
+	}
+
+	current := w.config.Load()
+	changes := Diff(current, next)
+	if len(changes) == 0 {
+		w.logger.Info("Config reloaded without changes")
+		return nil
+	}
+
+	var restart []Change
+	for _, change := range changes {
+		if !change.Hot {
+			restart = append(restart, change)
+		}
+	}
+	if len(restart) > 0 {
+		return &RestartRequiredError{Changes: restart}
+	}
+
+	w.config.Store(next)
+	w.logger.WithField("changes", formatChanges(changes)).Info("Applied config changes")
+
+	for _, event := range changeEvents(current, next, changes) {
+		for _, fn := range w.subscribers {
+			fn(event)
+		}
+	}
+	return nil
+}
+
+// changeEvents returns the events describing changes
+func changeEvents(old, new *Config, changes []Change) []Event {
+	var logLevel, rateLimit, features bool
+	for _, change := range changes {
+		switch {
+		case change.Key == "logging.level":
+			logLevel = true
+		case strings.HasPrefix(change.Key, "rate_limit."):
+			rateLimit = true
+		case change.Key == "features" || strings.HasPrefix(change.Key, "features."):
+			features = true
+		}
+	}
+
+	var events []Event
+	if logLevel {
+		events = append(events, LogLevelChanged{Old: old.Logging.Level, New: new.Logging.Level})
+	}
+	if rateLimit {
+		events = append(events, RateLimitChanged{Old: old.RateLimit, New: new.RateLimit})
+	}
+	if features {
+		events = append(events, FeaturesChanged{Old: old.Features, New: new.Features})
+	}
+	return events
+}
+
+func formatChanges(changes []Change) string {
+	parts := make([]string, len(changes))
+	for i, change := range changes {
+		parts[i] = change.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Name returns the component name
+func (w *Watcher) Name() string {
+	return "config watcher"
+}
+
+// Start watches the config files and SIGHUP, and blocks until the watcher is
+// stopped. Directories are watched rather than files so editor saves and
+// Kubernetes ConfigMap updates, which replace files, are detected.
+func (w *Watcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	w.watcher = watcher
+
+	// Config files and overlays are watched even if they do not exist yet,
+	// like every config file of the config directory
+	watched := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, file := range w.options.Files {
+		for _, path := range []string{file, overlayPath(file, w.options.Env)} {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				watcher.Close()
+				return fmt.Errorf("failed to resolve %s: %w", path, err)
+			}
+			watched[abs] = true
+			dirs[filepath.Dir(abs)] = true
+		}
+	}
+	configDir, err := filepath.Abs(w.options.dir())
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to resolve %s: %w", w.options.dir(), err)
+	}
+	if info, err := os.Stat(configDir); err == nil && info.IsDir() {
+		dirs[configDir] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	changed := func(path string) bool {
+		return watched[path] || filepath.Base(path) == kubernetesDataDir ||
+			filepath.Dir(path) == configDir && isConfigFile(path)
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	w.ready.Store(true)
+	defer w.ready.Store(false)
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if changed(event.Name) {
+				debounce = time.After(reloadDebounce)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Errorf("Config watcher error: %v", err)
+
+		case <-hangup:
+			w.logger.Info("Received SIGHUP, reloading config")
+			w.reload()
+
+		case <-debounce:
+			w.reload()
+		}
+	}
+}
+
+// reload reloads the config and logs why it was not applied
+func (w *Watcher) reload() {
+	err := w.Reload()
+	var restart *RestartRequiredError
+	switch {
+	case errors.As(err, &restart):
+		w.logger.WithField("changes", formatChanges(restart.Changes)).
+			Warn("Refusing config reload, these keys only change on restart")
+	case err != nil:
+		w.logger.Errorf("Keeping current config: %v", err)
+	}
+}
+
+// Stop stops watching the config files
+func (w *Watcher) Stop(ctx context.Context) error {
+	if w.watcher == nil {
+		return nil
+	}
+	return w.watcher.Close()
+}
+
+// Ready reports whether the config files are being watched
+func (w *Watcher) Ready() bool {
+	return w.ready.Load()
+}
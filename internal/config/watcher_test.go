@@ -0,0 +1,264 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   []Change
+	}{
+		{
+			name:   "unchanged",
+			change: func(cfg *Config) {},
+		},
+		{
+			name:   "hot key",
+			change: func(cfg *Config) { cfg.Logging.Level = "debug" },
+			want:   []Change{{Key: "logging.level", Old: "info", New: "debug", Hot: true}},
+		},
+		{
+			name:   "restart key",
+			change: func(cfg *Config) { cfg.Server.HTTP.Port = 9090 },
+			want:   []Change{{Key: "server.http.port", Old: 8085, New: 9090}},
+		},
+		{
+			name:   "duration",
+			change: func(cfg *Config) { cfg.Cache.DefaultTTL = time.Minute },
+			want:   []Change{{Key: "cache.default_ttl", Old: "5m0s", New: "1m0s"}},
+		},
+		{
+			name:   "secret",
+			change: func(cfg *Config) { cfg.Database.Password = "other" },
+			want:   []Change{{Key: "database.password", Old: redactedValue, New: redactedValue}},
+		},
+		{
+			// Map entries are compared one by one, the tag of the map applies to them
+			name: "map entries",
+			change: func(cfg *Config) {
+				cfg.Features = map[string]bool{"kept": true, "toggled": true, "added": true}
+			},
+			want: []Change{
+				{Key: "features.added", New: true, Hot: true},
+				{Key: "features.removed", Old: true, Hot: true},
+				{Key: "features.toggled", Old: false, New: true, Hot: true},
+			},
+		},
+		{
+			// Slices are compared as a whole
+			name: "slice",
+			change: func(cfg *Config) {
+				cfg.RateLimit.Overrides = append(cfg.RateLimit.Overrides, RateLimitOverride{Route: "GET /v1/health", Rate: 1, Burst: 1})
+			},
+			want: []Change{{
+				Key: "rate_limit.overrides",
+				Old: []any{map[string]any{"method": "/a/b", "route": "", "rate": 2.0, "burst": 2}},
+				New: []any{
+					map[string]any{"method": "/a/b", "route": "", "rate": 2.0, "burst": 2},
+					map[string]any{"method": "", "route": "GET /v1/health", "rate": 1.0, "burst": 1},
+				},
+				Hot: true,
+			}},
+		},
+		{
+			name: "several keys",
+			change: func(cfg *Config) {
+				cfg.Server.GRPC.Port = 9091
+				cfg.Logging.Level = "warn"
+			},
+			want: []Change{
+				{Key: "logging.level", Old: "info", New: "warn", Hot: true},
+				{Key: "server.grpc.port", Old: 8086, New: 9091},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &Config{}
+			old.Logging.Level = "info"
+			old.Server.HTTP.Port = 8085
+			old.Server.GRPC.Port = 8086
+			old.Cache.DefaultTTL = 5 * time.Minute
+			old.Database.Password = "secret"
+			old.Features = map[string]bool{"kept": true, "toggled": false, "removed": true}
+			old.RateLimit.Overrides = []RateLimitOverride{{Method: "/a/b", Rate: 2, Burst: 2}}
+
+			// Copy the maps and slices so changes do not alter old
+			new := *old
+			new.Features = map[string]bool{}
+			for name, enabled := range old.Features {
+				new.Features[name] = enabled
+			}
+			new.RateLimit.Overrides = append([]RateLimitOverride(nil), old.RateLimit.Overrides...)
+			tt.change(&new)
+
+			if got := Diff(old, &new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestWatcher loads the config selected by opts and creates a watcher of it
+func newTestWatcher(t *testing.T, opts LoadOptions) *Watcher {
+	t.Helper()
+	cfg, err := Load(context.Background(), testLogger(), opts)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return NewWatcher(WatcherParams{Logger: testLogger(), Options: opts, Config: cfg})
+}
+
+// writeWatchedConfig writes config.yaml in dir with the log level and HTTP port
+func writeWatchedConfig(t *testing.T, dir, level string, port int) string {
+	t.Helper()
+	return writeFile(t, dir, "config.yaml", fmt.Sprintf("cache:\n  type: memory\nlogging:\n  level: %s\nserver:\n  http:\n    port: %d\n", level, port))
+}
+
+func TestWatcherReload(t *testing.T) {
+	dir := t.TempDir()
+	path := writeWatchedConfig(t, dir, "info", 8085)
+	w := newTestWatcher(t, LoadOptions{Files: []string{path}, Env: "test"})
+	var events []Event
+	w.Subscribe(func(event Event) { events = append(events, event) })
+
+	// Unchanged files apply nothing
+	if err := w.Reload(); err != nil || len(events) != 0 {
+		t.Fatalf("Reload() unchanged = %v with events %v", err, events)
+	}
+
+	// Hot keys are applied and published
+	writeWatchedConfig(t, dir, "debug", 8085)
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() of a hot key error = %v", err)
+	}
+	if want := []Event{LogLevelChanged{Old: "info", New: "debug"}}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if got := w.Config().Logging.Level; got != "debug" {
+		t.Errorf("logging.level = %q, want debug", got)
+	}
+
+	// A reload changing a key that is not hot is refused as a whole
+	events = nil
+	writeWatchedConfig(t, dir, "warn", 9090)
+	err := w.Reload()
+	var restart *RestartRequiredError
+	if !errors.As(err, &restart) {
+		t.Fatalf("Reload() of a restart key error = %v, want a RestartRequiredError", err)
+	}
+	if want := []Change{{Key: "server.http.port", Old: 8085, New: 9090}}; !reflect.DeepEqual(restart.Changes, want) {
+		t.Errorf("Changes = %v, want %v", restart.Changes, want)
+	}
+	if err.Error() != "restart required to change server.http.port" {
+		t.Errorf("Error() = %q", err)
+	}
+	if cfg := w.Config(); cfg.Logging.Level != "debug" || cfg.Server.HTTP.Port != 8085 || len(events) != 0 {
+		t.Errorf("config = %s, %d with events %v, want the running config kept", cfg.Logging.Level, cfg.Server.HTTP.Port, events)
+	}
+
+	// Invalid files keep the running config
+	writeFile(t, dir, "config.yaml", "logging:\n  level: loud\n")
+	if err := w.Reload(); err == nil {
+		t.Errorf("Reload() of an invalid config error = nil")
+	}
+	if got := w.Config().Logging.Level; got != "debug" {
+		t.Errorf("logging.level = %q after an invalid reload, want debug", got)
+	}
+}
+
+func TestChangeEvents(t *testing.T) {
+	old := &Config{Features: map[string]bool{"a": true}}
+	old.Logging.Level = "info"
+	old.RateLimit.Rate = 1
+	new := &Config{Features: map[string]bool{"a": false}}
+	new.Logging.Level = "info"
+	new.RateLimit.Rate = 2
+
+	want := []Event{
+		RateLimitChanged{Old: old.RateLimit, New: new.RateLimit},
+		FeaturesChanged{Old: old.Features, New: new.Features},
+	}
+	if got := changeEvents(old, new, Diff(old, new)); !reflect.DeepEqual(got, want) {
+		t.Errorf("changeEvents() = %v, want %v", got, want)
+	}
+}
+
+// startWatcher starts w and waits until it watches the files
+func startWatcher(t *testing.T, w *Watcher) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- w.Start(context.Background()) }()
+	t.Cleanup(func() {
+		w.Stop(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	})
+	for deadline := time.Now().Add(5 * time.Second); !w.Ready(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("watcher not ready")
+		}
+	}
+}
+
+// waitEvent returns the next event published to events
+func waitEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no config event")
+		return nil
+	}
+}
+
+func TestWatcherWatchesFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeWatchedConfig(t, dir, "info", 8085)
+	w := newTestWatcher(t, LoadOptions{Files: []string{path}, Env: "test"})
+	events := make(chan Event, 10)
+	w.Subscribe(func(event Event) { events <- event })
+	startWatcher(t, w)
+
+	writeWatchedConfig(t, dir, "debug", 8085)
+	if got, want := waitEvent(t, events), (LogLevelChanged{Old: "info", New: "debug"}); got != want {
+		t.Errorf("event = %v, want %v", got, want)
+	}
+
+	// Overlays of the environment are watched even when created later
+	writeFile(t, dir, "config.test.yaml", "logging:\n  level: warn\n")
+	if got, want := waitEvent(t, events), (LogLevelChanged{Old: "debug", New: "warn"}); got != want {
+		t.Errorf("event = %v, want %v", got, want)
+	}
+}
+
+func TestWatcherReloadsOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := writeWatchedConfig(t, dir, "info", 8085)
+	// The remote store is not watched, SIGHUP rereads it
+	kv := writeFile(t, t.TempDir(), "kv.yaml", "logging/level: info\n")
+	w := newTestWatcher(t, LoadOptions{Files: []string{path}, Env: "test", Remote: "file://" + kv})
+	events := make(chan Event, 10)
+	w.Subscribe(func(event Event) { events <- event })
+	startWatcher(t, w)
+
+	writeFile(t, filepath.Dir(kv), "kv.yaml", "logging/level: error\n")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := waitEvent(t, events), (LogLevelChanged{Old: "info", New: "error"}); got != want {
+		t.Errorf("event = %v, want %v", got, want)
+	}
+}
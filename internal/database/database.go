@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotFound is returned by repositories when a row does not exist
+var ErrNotFound = errors.New("not found")
+
+// Querier is implemented by both the pool and transactions, so queries run
+// the same way inside and outside of a transaction
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// DB is a server component owning the PostgreSQL connection pool. It pings
+// the database on start, so the server fails fast when it is unreachable, and
+// closes the pool on shutdown.
+type DB struct {
+	pool   *pgxpool.Pool
+	config *config.DatabaseConfig
+	logger *logrus.Logger
+	stop   chan struct{}
+	ready  atomic.Bool
+}
+
+// New creates the connection pool, connections are established lazily
+func New(cfg *config.DatabaseConfig, logger *logrus.Logger) (*DB, error) {
+	poolConfig, err := pgxpool.ParseConfig(DSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database pool: %w", err)
+	}
+
+	return &DB{
+		pool:   pool,
+		config: cfg,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+// DSN builds the connection URL from the configuration
+func DSN(cfg *config.DatabaseConfig) string {
+	query := url.Values{}
+	for key, value := range cfg.Params {
+		query.Set(key, value)
+	}
+	if cfg.SSLMode != "" {
+		query.Set("sslmode", cfg.SSLMode)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password.Value()),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:     "/" + cfg.Name,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+// Pool returns the underlying connection pool
+func (db *DB) Pool() *pgxpool.Pool {
+	return db.pool
+}
+
+// Name returns the component name
+func (db *DB) Name() string {
+	return "database"
+}
+
+// Start verifies the database is reachable and blocks until the component is
+// stopped
+func (db *DB) Start(ctx context.Context) error {
+	if err := db.Ping(ctx); err != nil {
+		return err
+	}
+
+	db.logger.Infof("Connected to database %s on %s", db.config.Name, net.JoinHostPort(db.config.Host, db.config.Port))
+	db.ready.Store(true)
+	<-db.stop
+	return nil
+}
+
+// Stop closes the pool, waiting for acquired connections to be released
+func (db *DB) Stop(ctx context.Context) error {
+	db.ready.Store(false)
+	close(db.stop)
+	db.pool.Close()
+	return nil
+}
+
+// Ready reports whether the database was reached and the pool is open
+func (db *DB) Ready() bool {
+	return db.ready.Load()
+}
+
+// Ping acquires a connection and checks the database responds
+func (db *DB) Ping(ctx context.Context) error {
+	if timeout := db.config.ConnectTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := db.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.DatabaseConfig
+		want string
+	}{
+		{
+			name: "minimal",
+			cfg:  config.DatabaseConfig{Host: "localhost", Port: "5432", User: "app", Name: "app"},
+			want: "postgres://app:@localhost:5432/app",
+		},
+		{
+			// Credentials are escaped and parameters sorted
+			name: "escaped",
+			cfg: config.DatabaseConfig{
+				Host:     "::1",
+				Port:     "5433",
+				User:     "app",
+				Password: "p@ss/word",
+				Name:     "app",
+				SSLMode:  "require",
+				Params:   map[string]string{"application_name": "voidkit"},
+			},
+			want: "postgres://app:p%40ss%2Fword@[::1]:5433/app?application_name=voidkit&sslmode=require",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DSN(&tt.cfg); got != tt.want {
+				t.Errorf("DSN() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	if err := NotFound(fmt.Errorf("query: %w", pgx.ErrNoRows)); err != ErrNotFound {
+		t.Errorf("NotFound() of no rows = %v, want ErrNotFound", err)
+	}
+	other := errors.New("other")
+	if err := NotFound(other); err != other {
+		t.Errorf("NotFound() = %v, want the error unchanged", err)
+	}
+	if err := NotFound(nil); err != nil {
+		t.Errorf("NotFound(nil) = %v", err)
+	}
+}
+
+func TestUnreachableDatabase(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	// Connections are established lazily, creating the pool succeeds
+	db, err := New(&config.DatabaseConfig{
+		Host:           "127.0.0.1",
+		Port:           "1",
+		User:           "app",
+		Name:           "app",
+		SSLMode:        "disable",
+		MaxConns:       1,
+		ConnectTimeout: time.Second,
+	}, logger)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Start fails fast instead of blocking
+	if err := db.Start(context.Background()); err == nil {
+		t.Fatal("Start() of an unreachable database error = nil")
+	}
+	if db.Ready() {
+		t.Error("Ready() = true for an unreachable database")
+	}
+	db.Stop(context.Background())
+}
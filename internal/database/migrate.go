@@ -0,0 +1,447 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// migrationFile matches <version>_<name>.(up|down).sql
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change read from SQL files
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// HasDown reports whether a down file exists, an empty one is valid
+	HasDown bool
+}
+
+// MigrationStatus describes a migration known to the files or the database
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+	// AppliedAt is zero for pending migrations
+	AppliedAt time.Time
+	// Missing reports an applied migration whose files no longer exist
+	Missing bool
+}
+
+// Migrator applies migrations in version order, each in its own transaction.
+// A session advisory lock keeps concurrent runners, such as replicas migrating
+// on start, from applying the same migrations.
+type Migrator struct {
+	db         *DB
+	table      string
+	migrations []Migration
+	logger     *logrus.Logger
+}
+
+// NewMigrator reads the migrations of fsys, tracking applied versions in table
+func NewMigrator(db *DB, fsys fs.FS, table string, logger *logrus.Logger) (*Migrator, error) {
+	migrations, err := ReadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		table:      table,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// ReadMigrations parses the migration files at the root of fsys, sorted by
+// version. Every version needs an up file, down files are optional.
+func ReadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	// files maps each version and direction to its file
+	files := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q, expected <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), err)
+		}
+		// Versions like 1 and 01 are the same
+		key := fmt.Sprintf("%d.%s", version, match[3])
+		if other, ok := files[key]; ok {
+			return nil, fmt.Errorf("migration files %s and %s have the same version", other, entry.Name())
+		}
+		files[key] = entry.Name()
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+			migration.HasDown = true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if _, ok := files[fmt.Sprintf("%d.up", migration.Version)]; !ok {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	var count int
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down reverts the last n applied migrations and returns how many were reverted
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	var count int
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && count < n; i-- {
+			if _, ok := applied[m.migrations[i].Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, m.migrations[i], false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// To migrates up or down so that exactly the migrations up to version are
+// applied, version 0 reverts every migration. It returns how many migrations
+// were applied or reverted.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if version != 0 && m.find(version) == nil {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	var count int
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			count++
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status lists every migration with whether it is applied, including applied
+// migrations whose files are missing. It only reads the database: it neither
+// takes the migration lock nor creates the migrations table, which is
+// reported as every migration pending when it does not exist.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", pgx.Identifier{m.table}.Sanitize()).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check migrations table: %w", err)
+	}
+	applied := map[int64]time.Time{}
+	if exists {
+		if applied, err = m.applied(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	for version, appliedAt := range applied {
+		if m.find(version) == nil {
+			statuses = append(statuses, MigrationStatus{
+				Version:   version,
+				Applied:   true,
+				AppliedAt: appliedAt,
+				Missing:   true,
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// find returns the migration with the given version, nil if there is none
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// locked runs fn on a dedicated connection holding the migration lock, with
+// the migrations table created and its applied versions loaded
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int64]time.Time) error) (err error) {
+	conn, err := m.db.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	// Advisory locks belong to the session, so the lock and every migration
+	// use the same connection
+	key := m.lockKey()
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if !acquired {
+		m.logger.Info("Waiting for another migration runner to release the lock")
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+	}
+	defer func() {
+		if _, unlockErr := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", key); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release migration lock: %w", unlockErr))
+		}
+	}()
+
+	table := pgx.Identifier{m.table}.Sanitize()
+	if _, err := conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, table)); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// applied reads the applied migration versions from the migrations table
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, fmt.Sprintf("SELECT version, applied_at FROM %s", pgx.Identifier{m.table}.Sanitize()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := map[int64]time.Time{}
+	var version int64
+	var appliedAt time.Time
+	if _, err := pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		applied[version] = appliedAt
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// apply runs the up or down SQL of a migration and records it in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration, up bool) error {
+	direction, sql := "up", migration.Up
+	if !up {
+		if !migration.HasDown {
+			return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+		direction, sql = "down", migration.Down
+	}
+
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		// Without arguments the statements are sent with the simple protocol,
+		// so a file may hold several of them
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+		table := pgx.Identifier{m.table}.Sanitize()
+		if up {
+			_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2)", table), migration.Version, migration.Name)
+			return err
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = $1", table), migration.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s %d_%s: %w", direction, migration.Version, migration.Name, err)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"version":   migration.Version,
+		"name":      migration.Name,
+		"direction": direction,
+		"duration":  time.Since(start).String(),
+	}).Info("Applied migration")
+	return nil
+}
+
+// lockKey derives the advisory lock key from the migrations table, so
+// services sharing a database but not a table do not block each other
+func (m *Migrator) lockKey() int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("migrations:" + m.table))
+	return int64(hash.Sum64())
+}
+
+// CreateMigration writes empty up and down files for a new migration in dir,
+// versioned by the current UTC time, and returns their paths
+func CreateMigration(dir, name string, now time.Time) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name must contain letters or digits")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+
+	base := fmt.Sprintf("%s_%s", now.UTC().Format("20060102150405"), name)
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s.sql", base, direction))
+		content := fmt.Sprintf("-- %s %s\n", base, direction)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, fmt.Errorf("failed to create migration file: %w", err)
+		}
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write migration file: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// MigrateOnStart is a server component applying pending migrations once the
+// database is reachable, so it must be registered after the database
+type MigrateOnStart struct {
+	migrator *Migrator
+	timeout  time.Duration
+	stop     chan struct{}
+	ready    atomic.Bool
+}
+
+// NewMigrateOnStart creates the component running migrator on start, giving
+// up once timeout elapses
+func NewMigrateOnStart(migrator *Migrator, timeout time.Duration) *MigrateOnStart {
+	return &MigrateOnStart{migrator: migrator, timeout: timeout, stop: make(chan struct{})}
+}
+
+// Name returns the component name
+func (c *MigrateOnStart) Name() string {
+	return "migrations"
+}
+
+// Start applies pending migrations and blocks until the component is stopped
+func (c *MigrateOnStart) Start(ctx context.Context) error {
+	// The timeout cancels the running migration, whose transaction is rolled back
+	upCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	count, err := c.migrator.Up(upCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	c.migrator.logger.Infof("Applied %d migrations on start", count)
+
+	c.ready.Store(true)
+	<-c.stop
+	return nil
+}
+
+// Stop releases Start
+func (c *MigrateOnStart) Stop(ctx context.Context) error {
+	c.ready.Store(false)
+	close(c.stop)
+	return nil
+}
+
+// Ready reports whether the migrations were applied
+func (c *MigrateOnStart) Ready() bool {
+	return c.ready.Load()
+}
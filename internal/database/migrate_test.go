@@ -0,0 +1,140 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/database/migrations"
+)
+
+func TestReadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20240102000000_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t (c);")},
+		"20240101000000_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+		"20240101000000_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		// Empty files are valid, like a down migration with nothing to revert
+		"20240103000000_noop.up.sql":   {Data: []byte("")},
+		"20240103000000_noop.down.sql": {Data: []byte("")},
+		// Directories and other files are ignored
+		"README.md":        {Data: []byte("migrations")},
+		"old/1_old.up.sql": {Data: []byte("SELECT 1;")},
+		"migrations.go":    {Data: []byte("package migrations")},
+	}
+
+	got, err := ReadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("ReadMigrations() error = %v", err)
+	}
+	want := []Migration{
+		{Version: 20240101000000, Name: "create_table", Up: "CREATE TABLE t (c int);", Down: "DROP TABLE t;", HasDown: true},
+		{Version: 20240102000000, Name: "add_index", Up: "CREATE INDEX i ON t (c);"},
+		{Version: 20240103000000, Name: "noop", HasDown: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadMigrations() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr string
+	}{
+		{
+			name:    "bad name",
+			files:   []string{"1_Create-Table.up.sql"},
+			wantErr: `invalid migration file name "1_Create-Table.up.sql"`,
+		},
+		{
+			name:    "missing direction",
+			files:   []string{"1_init.sql"},
+			wantErr: `invalid migration file name "1_init.sql"`,
+		},
+		{
+			name:    "missing version",
+			files:   []string{"init.up.sql"},
+			wantErr: `invalid migration file name "init.up.sql"`,
+		},
+		{
+			name:    "version out of range",
+			files:   []string{"99999999999999999999_init.up.sql"},
+			wantErr: `invalid migration version in "99999999999999999999_init.up.sql"`,
+		},
+		{
+			name:    "down without up",
+			files:   []string{"1_init.up.sql", "2_users.down.sql"},
+			wantErr: "migration 2_users has no up file",
+		},
+		{
+			name:    "conflicting names",
+			files:   []string{"1_init.up.sql", "1_users.down.sql"},
+			wantErr: `migration 1 has conflicting names "init" and "users"`,
+		},
+		{
+			name:    "duplicate version",
+			files:   []string{"01_init.up.sql", "1_init.up.sql"},
+			wantErr: "migration files 01_init.up.sql and 1_init.up.sql have the same version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, file := range tt.files {
+				fsys[file] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+			}
+			_, err := ReadMigrations(fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadMigrations() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShippedMigrations(t *testing.T) {
+	got, err := ReadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("ReadMigrations() error = %v", err)
+	}
+	for _, migration := range got {
+		if !migration.HasDown {
+			t.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	paths, err := CreateMigration(dir, "Add users!", now)
+	if err != nil {
+		t.Fatalf("CreateMigration() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "20240102020405_add_users.up.sql"),
+		filepath.Join(dir, "20240102020405_add_users.down.sql"),
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("CreateMigration() = %v, want %v", paths, want)
+	}
+
+	// The created files read back as a migration
+	got, err := ReadMigrations(os.DirFS(dir))
+	if err != nil || len(got) != 1 || got[0].Name != "add_users" || !got[0].HasDown {
+		t.Errorf("ReadMigrations() = %+v, %v, want the created migration", got, err)
+	}
+
+	// Existing files are not overwritten
+	if _, err := CreateMigration(dir, "add users", now); err == nil {
+		t.Errorf("CreateMigration() of an existing migration error = nil")
+	}
+	if _, err := CreateMigration(dir, "!!", now); err == nil {
+		t.Errorf("CreateMigration() without a name error = nil")
+	}
+}
@@ -0,0 +1,2 @@
+-- Baseline of the schema, the tables of the service are created by the
+-- migrations that follow
@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL migrations of the service. Files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, new ones are created
+// with the migrate create subcommand.
+package migrations
+
+import "embed"
+
+// FS holds the migration files
+//
+//go:embed *.sql
+var FS embed.FS
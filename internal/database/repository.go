@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrDisabled is returned by the queries of a repository without a database
+var ErrDisabled = errors.New("database is disabled")
+
+// Repository is the base of the data access types of services. Embedding it
+// gives them Q, which joins the transaction of the context when there is one.
+// The zero Repository has no database, its queries fail with ErrDisabled.
+type Repository struct {
+	db *DB
+}
+
+// NewRepository creates a repository base on db
+func NewRepository(db *DB) Repository {
+	return Repository{db: db}
+}
+
+// DB returns the database the repository runs on, nil when it has none
+func (r Repository) DB() *DB {
+	return r.db
+}
+
+// Q returns the querier for ctx, the current transaction or the pool
+func (r Repository) Q(ctx context.Context) Querier {
+	if r.db == nil {
+		return disabledQuerier{}
+	}
+	return r.db.Querier(ctx)
+}
+
+// WithTx runs fn in a transaction, see DB.WithTx
+func (r Repository) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.db == nil {
+		return ErrDisabled
+	}
+	return r.db.WithTx(ctx, fn)
+}
+
+// disabledQuerier is the querier of a repository without a database
+type disabledQuerier struct{}
+
+func (disabledQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ErrDisabled
+}
+
+func (disabledQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, ErrDisabled
+}
+
+func (disabledQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return disabledRow{}
+}
+
+// disabledRow is the row of a disabled querier, failing to scan
+type disabledRow struct{}
+
+func (disabledRow) Scan(dest ...any) error {
+	return ErrDisabled
+}
+
+// NotFound converts pgx.ErrNoRows into ErrNotFound, leaving other errors as is
+func NotFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
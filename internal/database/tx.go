@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// txKey is the context key of the current transaction
+type txKey struct{}
+
+// TxFromContext returns the transaction carried by ctx, if any
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// Querier returns the transaction carried by ctx, or the pool when ctx is not
+// part of a transaction
+func (db *DB) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db.pool
+}
+
+// WithTx runs fn in a transaction carried by the context passed to fn, so
+// repositories called from fn share it. The transaction is committed when fn
+// returns nil and rolled back otherwise, including on panic. Nested calls run
+// in a savepoint of the outer transaction.
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return db.WithTxOptions(ctx, pgx.TxOptions{}, fn)
+}
+
+// WithTxOptions is like WithTx with the given isolation and access modes,
+// which are ignored for nested calls
+func (db *DB) WithTxOptions(ctx context.Context, opts pgx.TxOptions, fn func(ctx context.Context) error) (err error) {
+	var tx pgx.Tx
+	if outer, ok := TxFromContext(ctx); ok {
+		tx, err = outer.Begin(ctx)
+	} else {
+		tx, err = db.pool.BeginTx(ctx, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(context.WithoutCancel(ctx)); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			return errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
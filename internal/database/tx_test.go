@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeTx records how a transaction ends, the methods it does not override
+// panic through the nil embedded interface
+type fakeTx struct {
+	pgx.Tx
+	parent     *fakeTx
+	commitErr  error
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{parent: tx}, nil
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+	tx.rolledBack = true
+	return nil
+}
+
+func TestWithTxNested(t *testing.T) {
+	db := &DB{}
+	outer := &fakeTx{}
+	ctx := context.WithValue(context.Background(), txKey{}, pgx.Tx(outer))
+
+	if got := db.Querier(ctx); got != outer {
+		t.Errorf("Querier() = %v, want the transaction of the context", got)
+	}
+
+	var savepoint *fakeTx
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		tx, ok := TxFromContext(ctx)
+		if !ok {
+			t.Fatal("TxFromContext() ok = false inside WithTx")
+		}
+		savepoint = tx.(*fakeTx)
+		// Repositories called from fn join the savepoint
+		if got := NewRepository(db).Q(ctx); got != tx {
+			t.Errorf("Q() = %v, want the savepoint", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	if savepoint.parent != outer || !savepoint.committed || outer.committed || outer.rolledBack {
+		t.Errorf("savepoint = %+v, outer = %+v, want the savepoint of the outer transaction released", savepoint, outer)
+	}
+}
+
+func TestWithTxRollback(t *testing.T) {
+	db := &DB{}
+	outer := &fakeTx{}
+	ctx := context.WithValue(context.Background(), txKey{}, pgx.Tx(outer))
+	errFn := errors.New("fn failed")
+
+	var tx *fakeTx
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		current, _ := TxFromContext(ctx)
+		tx = current.(*fakeTx)
+		return errFn
+	})
+	if !errors.Is(err, errFn) {
+		t.Errorf("WithTx() error = %v, want the error of fn", err)
+	}
+	if !tx.rolledBack || tx.committed || outer.rolledBack {
+		t.Errorf("tx = %+v, want it rolled back alone", tx)
+	}
+
+	// fn may roll back itself, the closed transaction is not reported
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		current, _ := TxFromContext(ctx)
+		current.Rollback(ctx)
+		return errFn
+	})
+	if err != errFn {
+		t.Errorf("WithTx() error = %v, want the error of fn only", err)
+	}
+}
+
+func TestWithTxPanic(t *testing.T) {
+	db := &DB{}
+	ctx := context.WithValue(context.Background(), txKey{}, pgx.Tx(&fakeTx{}))
+
+	var tx *fakeTx
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recover() = %v, want the panic of fn", r)
+		}
+		if !tx.rolledBack {
+			t.Error("transaction not rolled back on panic")
+		}
+	}()
+	db.WithTx(ctx, func(ctx context.Context) error {
+		current, _ := TxFromContext(ctx)
+		tx = current.(*fakeTx)
+		panic("boom")
+	})
+}
+
+func TestWithTxCommitError(t *testing.T) {
+	db := &DB{}
+	errCommit := errors.New("serialization failure")
+	outer := &fakeTx{}
+	ctx := context.WithValue(context.Background(), txKey{}, pgx.Tx(outer))
+
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		current, _ := TxFromContext(ctx)
+		current.(*fakeTx).commitErr = errCommit
+		return nil
+	})
+	if !errors.Is(err, errCommit) {
+		t.Errorf("WithTx() error = %v, want the commit error", err)
+	}
+}
@@ -0,0 +1,486 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Lifetime controls how many instances of a type a provider creates
+type Lifetime int
+
+const (
+	// Singleton providers are called once per container
+	Singleton Lifetime = iota
+	// Scoped providers are called once per scope, such as a request
+	Scoped
+)
+
+func (l Lifetime) String() string {
+	if l == Scoped {
+		return "scoped"
+	}
+	return "singleton"
+}
+
+var (
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	containerType = reflect.TypeOf((*Container)(nil))
+	lifecycleType = reflect.TypeOf((*Lifecycle)(nil))
+)
+
+// Option configures a provider
+type Option func(*provider)
+
+// WithLifetime sets the lifetime of the instances, Singleton by default
+func WithLifetime(lifetime Lifetime) Option {
+	return func(p *provider) {
+		p.lifetime = lifetime
+	}
+}
+
+// As registers the provider for the interface T instead of the type it returns
+func As[T any]() Option {
+	return func(p *provider) {
+		p.as = reflect.TypeOf((*T)(nil)).Elem()
+	}
+}
+
+// provider creates the instances of a type by calling a constructor with
+// the instances of its parameter types
+type provider struct {
+	typ      reflect.Type
+	as       reflect.Type
+	fn       reflect.Value
+	params   []reflect.Type
+	hasErr   bool
+	lifetime Lifetime
+}
+
+// instance is an instance being created or created, waiters of a type
+// another caller is creating block on done
+type instance struct {
+	done  chan struct{}
+	value reflect.Value
+	err   error
+}
+
+// CycleError is returned when providers depend on each other
+type CycleError struct {
+	// Path lists the types of the cycle, starting and ending with the same type
+	Path []reflect.Type
+}
+
+func (e *CycleError) Error() string {
+	names := make([]string, len(e.Path))
+	for i, t := range e.Path {
+		names[i] = t.String()
+	}
+	return "dependency cycle: " + strings.Join(names, " -> ")
+}
+
+// Container creates instances of registered types, resolving the parameters
+// of their constructors from the other providers. A constructor may take a
+// *Lifecycle to run hooks when the container starts and stops, or the
+// *Container resolving it.
+//
+// The root container holds the providers and the singletons, a scope created
+// by Scope holds the scoped instances of a unit of work such as a request.
+// Constructors run without holding a lock, so they may resolve other types
+// from the container, and each type is created once even when resolved
+// concurrently.
+type Container struct {
+	// root is the container of a scope, nil for the root container
+	root *Container
+	// mu guards the instances of the container, and the providers of the root
+	mu        sync.Mutex
+	providers map[reflect.Type]*provider
+	order     []reflect.Type
+	instances map[reflect.Type]*instance
+	// created holds the instances in creation order, closed in reverse by
+	// Close
+	created []reflect.Value
+
+	lifecycle *Lifecycle
+	started   atomic.Bool
+	stopped   chan struct{}
+	stopOnce  sync.Once
+}
+
+// New creates an empty root container
+func New() *Container {
+	return &Container{
+		providers: map[reflect.Type]*provider{},
+		instances: map[reflect.Type]*instance{},
+		lifecycle: &Lifecycle{},
+		stopped:   make(chan struct{}),
+	}
+}
+
+// Provide registers a constructor, a function returning the provided type and
+// optionally an error, whose parameters are resolved from the container
+func (c *Container) Provide(constructor any, opts ...Option) error {
+	return c.register(constructor, false, opts)
+}
+
+// Override replaces the provider of the type returned by constructor, so
+// tests can swap services for fakes. It must be called before the type is
+// resolved.
+func (c *Container) Override(constructor any, opts ...Option) error {
+	return c.register(constructor, true, opts)
+}
+
+// Supply registers value as the instance of T
+func Supply[T any](c *Container, value T) error {
+	return c.Provide(func() T { return value })
+}
+
+func (c *Container) register(constructor any, override bool, opts []Option) error {
+	if c.root != nil {
+		return errors.New("providers are registered with the root container")
+	}
+
+	fn := reflect.ValueOf(constructor)
+	t := fn.Type()
+	if t.Kind() != reflect.Func || t.NumOut() < 1 || t.NumOut() > 2 || t.NumOut() == 2 && t.Out(1) != errorType {
+		return fmt.Errorf("invalid constructor %s, expected a function returning a value and optionally an error", t)
+	}
+	p := &provider{
+		typ:    t.Out(0),
+		fn:     fn,
+		hasErr: t.NumOut() == 2,
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		p.params = append(p.params, t.In(i))
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.as != nil {
+		if !p.typ.AssignableTo(p.as) {
+			return fmt.Errorf("%s does not implement %s", p.typ, p.as)
+		}
+		p.typ = p.as
+	}
+	if p.typ == containerType || p.typ == lifecycleType {
+		return fmt.Errorf("%s is provided by the container", p.typ)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, exists := c.providers[p.typ]
+	switch {
+	case exists && !override:
+		return fmt.Errorf("%s is already provided", p.typ)
+	case !exists && override:
+		return fmt.Errorf("%s has no provider to override", p.typ)
+	case override:
+		if _, created := c.instances[p.typ]; created {
+			return fmt.Errorf("%s is already created and cannot be overridden", p.typ)
+		}
+	default:
+		c.order = append(c.order, p.typ)
+	}
+	c.providers[p.typ] = p
+	return nil
+}
+
+// Resolve returns the instance of T, creating it and its dependencies when
+// needed. Scoped types are only resolved from a scope.
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	value, err := c.Resolve(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return zero, err
+	}
+	// Nil interfaces are valid instances, such as a disabled client
+	instance, _ := value.Interface().(T)
+	return instance, nil
+}
+
+// Resolve returns the instance of t, see the Resolve function
+func (c *Container) Resolve(t reflect.Type) (reflect.Value, error) {
+	return c.resolve(t, nil)
+}
+
+// Populate sets every exported field of the struct target points to with
+// the instance of its type
+func (c *Container) Populate(target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot populate %T, expected a pointer to a struct", target)
+	}
+	v = v.Elem()
+
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value, err := c.resolve(field.Type, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to populate %s: %w", field.Name, err))
+			continue
+		}
+		v.Field(i).Set(value)
+	}
+	return errors.Join(errs...)
+}
+
+// resolve returns the instance of t, path holding the types being created
+// that depend on it
+func (c *Container) resolve(t reflect.Type, path []reflect.Type) (reflect.Value, error) {
+	switch t {
+	case containerType:
+		return reflect.ValueOf(c), nil
+	case lifecycleType:
+		if c.root != nil {
+			return reflect.Value{}, fmt.Errorf("%s cannot be resolved from a scope%s", t, requiredBy(path))
+		}
+		return reflect.ValueOf(c.lifecycle), nil
+	}
+
+	for i, dependent := range path {
+		if dependent == t {
+			cycle := append(append([]reflect.Type{}, path[i:]...), t)
+			return reflect.Value{}, &CycleError{Path: cycle}
+		}
+	}
+
+	root := c.rootContainer()
+	root.mu.Lock()
+	p, ok := root.providers[t]
+	root.mu.Unlock()
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("no provider for %s%s", t, requiredBy(path))
+	}
+
+	// Singletons and their dependencies are resolved from the root
+	owner := c
+	if p.lifetime == Singleton {
+		owner = root
+	} else if c.root == nil {
+		return reflect.Value{}, fmt.Errorf("scoped %s cannot be resolved outside a scope%s", t, requiredBy(path))
+	}
+
+	// The first caller creates the instance, the others wait for it
+	owner.mu.Lock()
+	if inst, ok := owner.instances[t]; ok {
+		owner.mu.Unlock()
+		<-inst.done
+		return inst.value, inst.err
+	}
+	inst := &instance{done: make(chan struct{})}
+	owner.instances[t] = inst
+	owner.mu.Unlock()
+
+	inst.value, inst.err = owner.create(p, append(path, t))
+
+	// Failed instances are forgotten so later callers retry
+	owner.mu.Lock()
+	if inst.err != nil {
+		delete(owner.instances, t)
+	} else {
+		owner.created = append(owner.created, inst.value)
+	}
+	owner.mu.Unlock()
+	close(inst.done)
+	return inst.value, inst.err
+}
+
+// create resolves the parameters of p and calls its constructor, path ending
+// with the provided type
+func (c *Container) create(p *provider, path []reflect.Type) (value reflect.Value, err error) {
+	args := make([]reflect.Value, len(p.params))
+	for i, param := range p.params {
+		arg, err := c.resolve(param, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = arg
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic creating %s: %v", p.typ, r)
+		}
+	}()
+	out := p.fn.Call(args)
+	if p.hasErr && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("failed to create %s: %w", p.typ, out[1].Interface().(error))
+	}
+	value = out[0]
+	if value.Type() != p.typ {
+		value = value.Convert(p.typ)
+	}
+	return value, nil
+}
+
+func requiredBy(path []reflect.Type) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return ", required by " + path[len(path)-1].String()
+}
+
+func (c *Container) rootContainer() *Container {
+	if c.root != nil {
+		return c.root
+	}
+	return c
+}
+
+// Validate checks the dependency graph without creating instances: every
+// parameter must have a provider, providers must not depend on themselves
+// and singletons must not depend on scoped types, which would outlive their
+// scope
+func (c *Container) Validate() error {
+	root := c.rootContainer()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	types := append([]reflect.Type{}, root.order...)
+	sort.Slice(types, func(i, j int) bool { return types[i].String() < types[j].String() })
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[reflect.Type]int{}
+	var errs []error
+	var visit func(t reflect.Type, path []reflect.Type)
+	visit = func(t reflect.Type, path []reflect.Type) {
+		switch state[t] {
+		case visiting:
+			for i, dependent := range path {
+				if dependent == t {
+					errs = append(errs, &CycleError{Path: append(append([]reflect.Type{}, path[i:]...), t)})
+					return
+				}
+			}
+			return
+		case visited:
+			return
+		}
+		state[t] = visiting
+		defer func() { state[t] = visited }()
+
+		p := root.providers[t]
+		path = append(path, t)
+		for _, param := range p.params {
+			if param == containerType {
+				continue
+			}
+			if param == lifecycleType {
+				if p.lifetime == Scoped {
+					errs = append(errs, fmt.Errorf("scoped %s cannot depend on %s", t, param))
+				}
+				continue
+			}
+			dependency, ok := root.providers[param]
+			if !ok {
+				errs = append(errs, fmt.Errorf("no provider for %s, required by %s", param, t))
+				continue
+			}
+			if p.lifetime == Singleton && dependency.lifetime == Scoped {
+				errs = append(errs, fmt.Errorf("singleton %s cannot depend on scoped %s", t, param))
+				continue
+			}
+			visit(param, path)
+		}
+	}
+	for _, t := range types {
+		visit(t, nil)
+	}
+	return errors.Join(errs...)
+}
+
+// Scope creates a scope of the root container, holding its own instances of
+// scoped types. It must be closed once its unit of work is done.
+func (c *Container) Scope() *Container {
+	return &Container{
+		root:      c.rootContainer(),
+		instances: map[reflect.Type]*instance{},
+	}
+}
+
+// Close closes the instances of a scope implementing io.Closer, in reverse
+// creation order
+func (c *Container) Close() error {
+	if c.root == nil {
+		return errors.New("only scopes are closed, the root container is stopped")
+	}
+	c.mu.Lock()
+	created := c.created
+	c.created = nil
+	c.instances = map[reflect.Type]*instance{}
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(created) - 1; i >= 0; i-- {
+		if closer, ok := created[i].Interface().(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close %s: %w", created[i].Type(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Name returns the component name
+func (c *Container) Name() string {
+	return "dependency container"
+}
+
+// Start validates the container, creates every singleton so their hooks are
+// appended, then runs the start hooks and blocks until the container is
+// stopped
+func (c *Container) Start(ctx context.Context) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	var singletons []reflect.Type
+	for _, t := range c.order {
+		if c.providers[t].lifetime == Singleton {
+			singletons = append(singletons, t)
+		}
+	}
+	c.mu.Unlock()
+	for _, t := range singletons {
+		if _, err := c.resolve(t, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := c.lifecycle.start(ctx); err != nil {
+		return err
+	}
+	c.started.Store(true)
+	defer c.started.Store(false)
+
+	select {
+	case <-c.stopped:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the started hooks in reverse order
+func (c *Container) Stop(ctx context.Context) error {
+	defer c.stopOnce.Do(func() { close(c.stopped) })
+	return c.lifecycle.stop(ctx)
+}
+
+// Ready reports whether every start hook ran
+func (c *Container) Ready() bool {
+	return c.started.Load()
+}
@@ -0,0 +1,352 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type config struct{ name string }
+
+type store struct{ cfg *config }
+
+type service struct{ store *store }
+
+// request is a scoped type recording when it is closed
+type request struct {
+	id     int
+	closed *[]int
+}
+
+func (r *request) Close() error {
+	*r.closed = append(*r.closed, r.id)
+	return nil
+}
+
+type handler struct {
+	req *request
+	svc *service
+}
+
+func (h *handler) Close() error {
+	*h.req.closed = append(*h.req.closed, -h.req.id)
+	return nil
+}
+
+type greeter interface{ Greet() string }
+
+type english struct{}
+
+func (english) Greet() string { return "hello" }
+
+func mustProvide(t *testing.T, c *Container, constructor any, opts ...Option) {
+	t.Helper()
+	if err := c.Provide(constructor, opts...); err != nil {
+		t.Fatalf("Provide() error = %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	c := New()
+	var calls atomic.Int32
+	mustProvide(t, c, func() *config {
+		calls.Add(1)
+		return &config{name: "app"}
+	})
+	mustProvide(t, c, func(cfg *config) *store { return &store{cfg: cfg} })
+	mustProvide(t, c, func(s *store) (*service, error) { return &service{store: s}, nil })
+	mustProvide(t, c, func() english { return english{} }, As[greeter]())
+
+	svc, err := Resolve[*service](c)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if svc.store.cfg.name != "app" {
+		t.Errorf("service config = %q, want app", svc.store.cfg.name)
+	}
+	again, _ := Resolve[*service](c)
+	cfg, _ := Resolve[*config](c)
+	if again != svc || cfg != svc.store.cfg || calls.Load() != 1 {
+		t.Errorf("singletons were created more than once")
+	}
+
+	g, err := Resolve[greeter](c)
+	if err != nil || g.Greet() != "hello" {
+		t.Errorf("Resolve[greeter]() = %v, %v, want the english greeter", g, err)
+	}
+
+	var target struct {
+		Service *service
+		Greeter greeter
+		skipped *store
+	}
+	if err := c.Populate(&target); err != nil {
+		t.Fatalf("Populate() error = %v", err)
+	}
+	if target.Service != svc || target.Greeter == nil || target.skipped != nil {
+		t.Errorf("Populate() = %+v, want the exported fields set", target)
+	}
+}
+
+func TestProvideErrors(t *testing.T) {
+	c := New()
+	mustProvide(t, c, func() *config { return &config{} })
+
+	tests := []struct {
+		name        string
+		constructor any
+		opts        []Option
+		wantErr     string
+	}{
+		{name: "not a function", constructor: &config{}, wantErr: "invalid constructor"},
+		{name: "second result not an error", constructor: func() (*config, int) { return nil, 0 }, wantErr: "invalid constructor"},
+		{name: "duplicate", constructor: func() *config { return nil }, wantErr: "*di.config is already provided"},
+		{name: "container type", constructor: func() *Container { return nil }, wantErr: "provided by the container"},
+		{name: "not implementing", constructor: func() *config { return nil }, opts: []Option{As[greeter]()}, wantErr: "does not implement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Provide(tt.constructor, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Provide() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := c.Scope().Provide(func() *store { return nil }); err == nil {
+		t.Errorf("Provide() on a scope error = nil")
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	c := New()
+	mustProvide(t, c, func(s *store) *config { return &config{} })
+	mustProvide(t, c, func(cfg *config) *store { return &store{} })
+	mustProvide(t, c, func(g greeter) *service { return &service{} })
+	mustProvide(t, c, func() (*handler, error) { return nil, errors.New("boom") })
+
+	_, err := Resolve[*config](c)
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Resolve() error = %v, want a CycleError", err)
+	}
+	if want := "dependency cycle: *di.config -> *di.store -> *di.config"; cycle.Error() != want {
+		t.Errorf("CycleError = %q, want %q", cycle, want)
+	}
+
+	if _, err := Resolve[*service](c); err == nil || err.Error() != "no provider for di.greeter, required by *di.service" {
+		t.Errorf("Resolve() error = %v, want a missing provider", err)
+	}
+	if _, err := Resolve[*handler](c); err == nil || err.Error() != "failed to create *di.handler: boom" {
+		t.Errorf("Resolve() error = %v, want the constructor error", err)
+	}
+}
+
+func TestResolveRetriesFailedConstructors(t *testing.T) {
+	c := New()
+	var calls int
+	mustProvide(t, c, func() (*config, error) {
+		calls++
+		if calls == 1 {
+			panic("not yet")
+		}
+		return &config{}, nil
+	})
+
+	if _, err := Resolve[*config](c); err == nil || !strings.Contains(err.Error(), "panic creating *di.config: not yet") {
+		t.Fatalf("Resolve() error = %v, want the recovered panic", err)
+	}
+	if _, err := Resolve[*config](c); err != nil {
+		t.Errorf("second Resolve() error = %v, want the constructor to run again", err)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	c := New()
+	mustProvide(t, c, func() *config { return &config{name: "real"} })
+	mustProvide(t, c, func(cfg *config) *store { return &store{cfg: cfg} })
+
+	if err := c.Override(func() *config { return &config{name: "fake"} }); err != nil {
+		t.Fatalf("Override() error = %v", err)
+	}
+	s, err := Resolve[*store](c)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if s.cfg.name != "fake" {
+		t.Errorf("store config = %q, want the override", s.cfg.name)
+	}
+
+	if err := c.Override(func() *config { return nil }); err == nil || !strings.Contains(err.Error(), "already created") {
+		t.Errorf("Override() of a created type error = %v", err)
+	}
+	if err := c.Override(func() *service { return nil }); err == nil || !strings.Contains(err.Error(), "no provider to override") {
+		t.Errorf("Override() without a provider error = %v", err)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	c := New()
+	var closed []int
+	var ids atomic.Int32
+	mustProvide(t, c, func() *store { return &store{} })
+	mustProvide(t, c, func(s *store) *service { return &service{store: s} })
+	mustProvide(t, c, func() *request {
+		return &request{id: int(ids.Add(1)), closed: &closed}
+	}, WithLifetime(Scoped))
+	mustProvide(t, c, func(r *request, s *service) *handler {
+		return &handler{req: r, svc: s}
+	}, WithLifetime(Scoped))
+
+	if _, err := Resolve[*request](c); err == nil || !strings.Contains(err.Error(), "scoped *di.request cannot be resolved outside a scope") {
+		t.Errorf("Resolve() of a scoped type from the root error = %v", err)
+	}
+
+	first, second := c.Scope(), c.Scope()
+	h1, err := Resolve[*handler](first)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	r1, _ := Resolve[*request](first)
+	h2, _ := Resolve[*handler](second)
+	if h1.req != r1 {
+		t.Errorf("a scope created its scoped type twice")
+	}
+	if h1 == h2 || h1.req == h2.req {
+		t.Errorf("scopes share scoped instances")
+	}
+	if h1.svc != h2.svc {
+		t.Errorf("scopes created their own singletons")
+	}
+
+	// Instances are closed in reverse creation order, handler before request
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if want := []int{-1, 1}; !reflect.DeepEqual(closed, want) {
+		t.Errorf("closed = %v, want %v", closed, want)
+	}
+	// A closed scope creates new instances
+	if r, _ := Resolve[*request](first); r == r1 {
+		t.Errorf("Resolve() after Close() returned a closed instance")
+	}
+	if err := c.Close(); err == nil {
+		t.Errorf("Close() of the root container error = nil")
+	}
+	if _, err := Resolve[*Lifecycle](first); err == nil {
+		t.Errorf("Resolve() of the lifecycle from a scope error = nil")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := New()
+	mustProvide(t, c, func(s *store) *config { return &config{} })
+	mustProvide(t, c, func(cfg *config) *store { return &store{} })
+	mustProvide(t, c, func(r *request) *service { return &service{} })
+	mustProvide(t, c, func() *request { return &request{} }, WithLifetime(Scoped))
+	mustProvide(t, c, func(l *Lifecycle, g greeter) *handler { return &handler{} }, WithLifetime(Scoped))
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Validate() error = nil")
+	}
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Errorf("Validate() error = %v, want a CycleError", err)
+	}
+	for _, want := range []string{
+		"dependency cycle: *di.config -> *di.store -> *di.config",
+		"singleton *di.service cannot depend on scoped *di.request",
+		"scoped *di.handler cannot depend on *di.Lifecycle",
+		"no provider for di.greeter, required by *di.handler",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %q, want it to contain %q", err, want)
+		}
+	}
+
+	valid := New()
+	mustProvide(t, valid, func() *config { return &config{} })
+	mustProvide(t, valid, func(cfg *config, c *Container) *store { return &store{} })
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestConcurrentResolve(t *testing.T) {
+	c := New()
+	var calls atomic.Int32
+	mustProvide(t, c, func() *config {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return &config{}
+	})
+	// A constructor may resolve from the container it is created by
+	mustProvide(t, c, func(c *Container) (*store, error) {
+		cfg, err := Resolve[*config](c)
+		return &store{cfg: cfg}, err
+	})
+
+	var wg sync.WaitGroup
+	results := make([]*store, 16)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = Resolve[*store](c)
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("config created %d times, want once", calls.Load())
+	}
+	for _, s := range results {
+		if s == nil || s != results[0] {
+			t.Fatalf("concurrent resolves returned different instances")
+		}
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	c := New()
+	var events []string
+	hook := func(name string) func(*Lifecycle) {
+		return func(l *Lifecycle) {
+			l.Append(Hook{
+				Name:    name,
+				OnStart: func(context.Context) error { events = append(events, "start "+name); return nil },
+				OnStop:  func(context.Context) error { events = append(events, "stop "+name); return nil },
+			})
+		}
+	}
+	mustProvide(t, c, func(l *Lifecycle) *config { hook("config")(l); return &config{} })
+	mustProvide(t, c, func(l *Lifecycle, cfg *config) *store { hook("store")(l); return &store{cfg: cfg} })
+
+	done := make(chan error, 1)
+	go func() { done <- c.Start(context.Background()) }()
+	deadline := time.Now().Add(time.Second)
+	for !c.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("container not ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Dependencies start first and stop last
+	want := []string{"start config", "start store", "stop store", "stop config"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
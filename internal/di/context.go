@@ -0,0 +1,16 @@
+package di
+
+import "context"
+
+type scopeKey struct{}
+
+// WithScope returns a context carrying scope
+func WithScope(ctx context.Context, scope *Container) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the scope of ctx, nil when there is none
+func ScopeFromContext(ctx context.Context) *Container {
+	scope, _ := ctx.Value(scopeKey{}).(*Container)
+	return scope
+}
@@ -0,0 +1,75 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Hook runs code when the container starts and stops, such as starting a
+// background worker and waiting for it to finish
+type Hook struct {
+	// Name identifies the hook in errors
+	Name string
+	// OnStart runs when the container starts, after the hooks appended before
+	OnStart func(ctx context.Context) error
+	// OnStop runs when the container stops, before the hooks appended before,
+	// and only when OnStart succeeded
+	OnStop func(ctx context.Context) error
+}
+
+// Lifecycle holds the hooks appended by constructors taking a *Lifecycle.
+// Dependencies are created first, so their hooks start first and stop last.
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+}
+
+// Append adds a hook
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// start runs the start hooks in order. On failure the hooks already started
+// are stopped.
+func (l *Lifecycle) start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, hook := range l.hooks[l.started:] {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				startErr := fmt.Errorf("failed to start %s: %w", hook.Name, err)
+				return errors.Join(startErr, l.stopLocked(context.WithoutCancel(ctx)))
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// stop runs the stop hooks of the started hooks in reverse order and
+// aggregates errors
+func (l *Lifecycle) stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stopLocked(ctx)
+}
+
+func (l *Lifecycle) stopLocked(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		hook := l.hooks[l.started-1]
+		if hook.OnStop == nil {
+			continue
+		}
+		if err := hook.OnStop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
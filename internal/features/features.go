@@ -0,0 +1,41 @@
+// Package features exposes the feature flags of the features config key,
+// which can be toggled without a restart when the config is reloaded
+package features
+
+import (
+	"maps"
+	"strings"
+	"sync/atomic"
+)
+
+// Flags holds the current feature flags, safe for concurrent use
+type Flags struct {
+	flags atomic.Pointer[map[string]bool]
+}
+
+// New creates flags holding the given values
+func New(flags map[string]bool) *Flags {
+	f := &Flags{}
+	f.Set(flags)
+	return f
+}
+
+// Enabled reports whether the named flag is on, unknown flags are off. Names
+// are case insensitive, as config keys are.
+func (f *Flags) Enabled(name string) bool {
+	return (*f.flags.Load())[strings.ToLower(name)]
+}
+
+// Set replaces every flag
+func (f *Flags) Set(flags map[string]bool) {
+	next := make(map[string]bool, len(flags))
+	for name, enabled := range flags {
+		next[strings.ToLower(name)] = enabled
+	}
+	f.flags.Store(&next)
+}
+
+// All returns a copy of the flags
+func (f *Flags) All() map[string]bool {
+	return maps.Clone(*f.flags.Load())
+}
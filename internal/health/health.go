@@ -0,0 +1,177 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultCheckTimeout bounds a single check when the caller has no deadline
+const defaultCheckTimeout = 5 * time.Second
+
+// Checker reports the health of a dependency, a nil error means healthy
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Kind tells which probe a check contributes to
+type Kind string
+
+const (
+	// Liveness checks fail only when the process must be restarted
+	Liveness Kind = "liveness"
+	// Readiness checks fail when the process should not receive traffic
+	Readiness Kind = "readiness"
+)
+
+// Result is the outcome of a single check
+type Result struct {
+	Name     string        `json:"name"`
+	Healthy  bool          `json:"healthy"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report is the aggregated outcome of every check of a kind
+type Report struct {
+	Healthy bool     `json:"healthy"`
+	Checks  []Result `json:"checks"`
+}
+
+type check struct {
+	kind    Kind
+	checker Checker
+}
+
+// Registry holds the named checks contributed by services and server components
+type Registry struct {
+	mu      sync.RWMutex
+	checks  map[string]check
+	timeout time.Duration
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		checks:  map[string]check{},
+		timeout: defaultCheckTimeout,
+	}
+}
+
+// RegisterLiveness adds a named liveness check, replacing any check with the same name
+func (r *Registry) RegisterLiveness(name string, checker Checker) {
+	r.register(name, Liveness, checker)
+}
+
+// RegisterReadiness adds a named readiness check, replacing any check with the same name
+func (r *Registry) RegisterReadiness(name string, checker Checker) {
+	r.register(name, Readiness, checker)
+}
+
+func (r *Registry) register(name string, kind Kind, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = check{kind: kind, checker: checker}
+}
+
+// Names returns the names of all registered checks in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Liveness runs every liveness check
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, Liveness)
+}
+
+// Readiness runs every readiness and liveness check, a process that is not
+// alive cannot be ready either
+func (r *Registry) Readiness(ctx context.Context) Report {
+	return r.run(ctx, "")
+}
+
+// Check runs the single check registered under name
+func (r *Registry) Check(ctx context.Context, name string) (Result, bool) {
+	r.mu.RLock()
+	c, ok := r.checks[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Result{}, false
+	}
+	return r.runCheck(ctx, name, c.checker), true
+}
+
+// run executes the matching checks concurrently, kind "" matches every check
+func (r *Registry) run(ctx context.Context, kind Kind) Report {
+	r.mu.RLock()
+	selected := map[string]Checker{}
+	for name, c := range r.checks {
+		if kind == "" || c.kind == kind {
+			selected[name] = c.checker
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make(chan Result, len(selected))
+	for name, checker := range selected {
+		go func() {
+			results <- r.runCheck(ctx, name, checker)
+		}()
+	}
+
+	report := Report{Healthy: true, Checks: make([]Result, 0, len(selected))}
+	for range selected {
+		result := <-results
+		report.Healthy = report.Healthy && result.Healthy
+		report.Checks = append(report.Checks, result)
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+
+	return report
+}
+
+// runCheck executes a single check with a timeout, converting panics into failures
+func (r *Registry) runCheck(ctx context.Context, name string, checker Checker) (result Result) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result = Result{Name: name}
+	defer func() {
+		if p := recover(); p != nil {
+			result.Healthy = false
+			result.Error = fmt.Sprintf("panic: %v", p)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	if err := checker.Check(ctx); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Healthy = true
+	return result
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestIDHeader is the HTTP header carrying the request ID
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDMetadataKey is the gRPC metadata key carrying the request ID
+	RequestIDMetadataKey = "x-request-id"
+	// maxRequestIDLength bounds request IDs accepted from callers
+	maxRequestIDLength = 128
+)
+
+type entryKey struct{}
+
+type requestIDKey struct{}
+
+// WithEntry returns a copy of ctx carrying the request-scoped log entry
+func WithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns the request-scoped log entry of ctx, or an entry of
+// fallback when ctx carries none
+func FromContext(ctx context.Context, fallback *logrus.Logger) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(fallback)
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID of ctx, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID reports whether a request ID received from a caller can be
+// propagated as is, only printable ASCII of bounded length is accepted
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
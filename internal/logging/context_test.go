@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		want      bool
+	}{
+		{name: "generated", requestID: NewRequestID(), want: true},
+		{name: "printable", requestID: "req-1/a:b_c~!", want: true},
+		{name: "longest", requestID: strings.Repeat("a", maxRequestIDLength), want: true},
+		{name: "empty"},
+		{name: "too long", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "space", requestID: "req 1"},
+		{name: "newline", requestID: "req\n1"},
+		{name: "control", requestID: "req\x001"},
+		{name: "non ASCII", requestID: "réq"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidRequestID(tt.requestID); got != tt.want {
+				t.Errorf("ValidRequestID(%q) = %v, want %v", tt.requestID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	if len(a) != 32 || a == b {
+		t.Errorf("NewRequestID() = %q, %q, want distinct 32 character IDs", a, b)
+	}
+}
+
+func TestContext(t *testing.T) {
+	logger := logrus.New()
+	ctx := context.Background()
+
+	if got := RequestIDFromContext(ctx); got != "" {
+		t.Errorf("RequestIDFromContext() = %q, want empty", got)
+	}
+	if got := FromContext(ctx, logger); got.Logger != logger || len(got.Data) != 0 {
+		t.Errorf("FromContext() = %v, want an entry of the fallback logger", got)
+	}
+
+	entry := logger.WithField("request_id", "abc")
+	ctx = WithEntry(WithRequestID(ctx, "abc"), entry)
+	if got := RequestIDFromContext(ctx); got != "abc" {
+		t.Errorf("RequestIDFromContext() = %q, want abc", got)
+	}
+	if got := FromContext(ctx, logrus.New()); got != entry {
+		t.Errorf("FromContext() = %v, want the request entry", got)
+	}
+}
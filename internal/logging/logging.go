@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Supported log outputs
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+)
+
+// New builds a logger from the logging configuration. The static fields are
+// added to every entry, values from the configuration take precedence.
+func New(cfg *config.LoggingConfig, fields logrus.Fields) (*logrus.Logger, error) {
+	logger := logrus.New()
+
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse log level: %w", err)
+	}
+	logger.SetLevel(level)
+
+	formatter, err := newFormatter(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+	logger.SetFormatter(formatter)
+
+	output, err := newOutput(cfg)
+	if err != nil {
+		return nil, err
+	}
+	logger.SetOutput(output)
+
+	logger.SetReportCaller(cfg.ReportCaller)
+
+	static := logrus.Fields{}
+	for key, value := range fields {
+		static[key] = value
+	}
+	for key, value := range cfg.Fields {
+		static[key] = value
+	}
+	if len(static) > 0 {
+		logger.AddHook(&fieldsHook{fields: static})
+	}
+
+	return logger, nil
+}
+
+// newFormatter returns the formatter for the configured format
+func newFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	case "text":
+		return &logrus.TextFormatter{FullTimestamp: true}, nil
+	}
+	return nil, fmt.Errorf("unsupported log format %q", format)
+}
+
+// newOutput returns the writer for the configured output, files are rotated
+// by size and age
+func newOutput(cfg *config.LoggingConfig) (io.Writer, error) {
+	switch cfg.Output {
+	case "", OutputStdout:
+		return os.Stdout, nil
+	case OutputStderr:
+		return os.Stderr, nil
+	case OutputFile:
+		if cfg.File.Path == "" {
+			return nil, fmt.Errorf("logging.file.path is required when logging.output is %s", OutputFile)
+		}
+		return &lumberjack.Logger{
+			Filename:   cfg.File.Path,
+			MaxSize:    cfg.File.MaxSizeMB,
+			MaxBackups: cfg.File.MaxBackups,
+			MaxAge:     cfg.File.MaxAgeDays,
+			Compress:   cfg.File.Compress,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported log output %q", cfg.Output)
+}
+
+// fieldsHook adds static fields to every entry without overriding fields set
+// at the call site
+type fieldsHook struct {
+	fields logrus.Fields
+}
+
+func (h *fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fieldsHook) Fire(entry *logrus.Entry) error {
+	for key, value := range h.fields {
+		if _, ok := entry.Data[key]; !ok {
+			entry.Data[key] = value
+		}
+	}
+	return nil
+}
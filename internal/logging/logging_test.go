@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewWritesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := New(&config.LoggingConfig{
+		Level:  "warn",
+		Format: "json",
+		Output: OutputFile,
+		File:   config.LogFileConfig{Path: path, MaxSizeMB: 1},
+		Fields: map[string]string{"service": "orders", "env": "configured"},
+	}, logrus.Fields{"env": "static", "version": "1.0.0"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Info("filtered")
+	logger.WithField("version", "call site").Warn("kept")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("log file has %d entries, want the warning only:\n%s", len(lines), content)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("entry is not JSON: %v", err)
+	}
+	// Configured fields take precedence over static ones, fields set at the
+	// call site over both
+	want := map[string]any{"msg": "kept", "level": "warning", "service": "orders", "env": "configured", "version": "call site"}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.LoggingConfig
+		wantErr string
+	}{
+		{name: "level", cfg: config.LoggingConfig{Level: "loud", Format: "json"}, wantErr: "failed to parse log level"},
+		{name: "format", cfg: config.LoggingConfig{Level: "info", Format: "xml"}, wantErr: `unsupported log format "xml"`},
+		{name: "output", cfg: config.LoggingConfig{Level: "info", Format: "json", Output: "syslog"}, wantErr: `unsupported log output "syslog"`},
+		{name: "file without path", cfg: config.LoggingConfig{Level: "info", Format: "text", Output: OutputFile}, wantErr: "logging.file.path is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&tt.cfg, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	logger, err := New(&config.LoggingConfig{Level: "debug", Format: "text", ReportCaller: true}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if logger.Out != os.Stdout || logger.GetLevel() != logrus.DebugLevel || !logger.ReportCaller {
+		t.Errorf("logger = %v at %s, want stdout at debug reporting callers", logger.Out, logger.GetLevel())
+	}
+	if _, ok := logger.Formatter.(*logrus.TextFormatter); !ok {
+		t.Errorf("formatter = %T, want text", logger.Formatter)
+	}
+	// Without static fields no hook is added
+	if len(logger.Hooks) != 0 {
+		t.Errorf("hooks = %v, want none", logger.Hooks)
+	}
+}
@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/buildinfo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// gRPC call types used as the grpc_type label
+const (
+	Unary        = "unary"
+	ClientStream = "client_stream"
+	ServerStream = "server_stream"
+	BidiStream   = "bidi_stream"
+)
+
+// Metrics holds the Prometheus registry and the server instrumentation
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight prometheus.Gauge
+
+	grpcHandled  *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+	grpcInFlight *prometheus.GaugeVec
+
+	cacheOperations *prometheus.CounterVec
+	cacheDuration   *prometheus.HistogramVec
+}
+
+// New creates a registry with Go runtime and process collectors along with
+// the build info and the HTTP and gRPC server metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_requests_total",
+			Help: "Total number of HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_request_duration_seconds",
+			Help:    "Latency of HTTP requests by method and route template.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_server_requests_in_flight",
+			Help: "Number of HTTP requests currently being served.",
+		}),
+		grpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of gRPC calls completed by method and status code.",
+		}, []string{"grpc_service", "grpc_method", "grpc_type", "grpc_code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Latency of gRPC calls by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"grpc_service", "grpc_method", "grpc_type"}),
+		grpcInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "grpc_server_in_flight",
+			Help: "Number of gRPC calls currently being served by method.",
+		}, []string{"grpc_service", "grpc_method"}),
+		cacheOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_operations_total",
+			Help: "Total number of cache operations by operation and result.",
+		}, []string{"operation", "result"}),
+		cacheDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cache_operation_duration_seconds",
+			Help:    "Latency of cache operations by operation.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+	}
+
+	info := buildinfo.Get()
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "build_info",
+		Help: "Build information of the running binary, always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":    info.Version,
+			"commit":     info.Commit,
+			"build_date": info.BuildDate,
+			"go_version": info.GoVersion,
+		},
+	})
+	buildInfo.Set(1)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo,
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.grpcHandled,
+		m.grpcDuration,
+		m.grpcInFlight,
+		m.cacheOperations,
+		m.cacheDuration,
+	)
+
+	return m
+}
+
+// Registerer returns the registerer for collectors contributed by other packages
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registry
+}
+
+// Handler returns the handler exposing the metrics in the Prometheus format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// HTTPStarted marks the start of an HTTP request and returns the function
+// recording its outcome
+func (m *Metrics) HTTPStarted() func(method, route string, status int, duration time.Duration) {
+	m.httpInFlight.Inc()
+	return func(method, route string, status int, duration time.Duration) {
+		m.httpInFlight.Dec()
+		m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+	}
+}
+
+// GRPCStarted marks the start of a gRPC call and returns the function
+// recording its outcome
+func (m *Metrics) GRPCStarted(fullMethod, callType string) func(code codes.Code, duration time.Duration) {
+	service, method := splitMethodName(fullMethod)
+	inFlight := m.grpcInFlight.WithLabelValues(service, method)
+	inFlight.Inc()
+	return func(code codes.Code, duration time.Duration) {
+		inFlight.Dec()
+		m.grpcHandled.WithLabelValues(service, method, callType, code.String()).Inc()
+		m.grpcDuration.WithLabelValues(service, method, callType).Observe(duration.Seconds())
+	}
+}
+
+// ObserveCache records the outcome of a cache operation
+func (m *Metrics) ObserveCache(operation, result string, duration time.Duration) {
+	m.cacheOperations.WithLabelValues(operation, result).Inc()
+	m.cacheDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// InitializeGRPC creates the series of every registered method so they are
+// exported with zero values before the first call
+func (m *Metrics) InitializeGRPC(services map[string]grpc.ServiceInfo) {
+	for service, info := range services {
+		for _, method := range info.Methods {
+			callType := StreamType(method.IsClientStream, method.IsServerStream)
+			m.grpcHandled.WithLabelValues(service, method.Name, callType, codes.OK.String())
+			m.grpcDuration.WithLabelValues(service, method.Name, callType)
+			m.grpcInFlight.WithLabelValues(service, method.Name)
+		}
+	}
+}
+
+// StreamType returns the grpc_type label of a call
+func StreamType(isClientStream, isServerStream bool) string {
+	switch {
+	case isClientStream && isServerStream:
+		return BidiStream
+	case isClientStream:
+		return ClientStream
+	case isServerStream:
+		return ServerStream
+	}
+	return Unary
+}
+
+// splitMethodName splits "/package.Service/Method" into service and method
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
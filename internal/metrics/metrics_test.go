@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// scrape returns the metrics exposed by m in the Prometheus text format
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func assertSeries(t *testing.T, body string, series ...string) {
+	t.Helper()
+	for _, s := range series {
+		if !strings.Contains(body, s+"\n") {
+			t.Errorf("metrics do not contain %s", s)
+		}
+	}
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	m := New()
+	done := m.HTTPStarted()
+	assertSeries(t, scrape(t, m), "http_server_requests_in_flight 1")
+
+	done(http.MethodGet, "/v1/items/{id}", http.StatusNotFound, 20*time.Millisecond)
+	assertSeries(t, scrape(t, m),
+		"http_server_requests_in_flight 0",
+		`http_server_requests_total{code="404",method="GET",route="/v1/items/{id}"} 1`,
+		`http_server_request_duration_seconds_count{method="GET",route="/v1/items/{id}"} 1`,
+		`http_server_request_duration_seconds_bucket{method="GET",route="/v1/items/{id}",le="0.025"} 1`,
+	)
+}
+
+func TestGRPCMetrics(t *testing.T) {
+	m := New()
+	// Registered methods are exported before their first call
+	m.InitializeGRPC(map[string]grpc.ServiceInfo{
+		"common.v1.CommonService": {Methods: []grpc.MethodInfo{{Name: "HealthCheck"}, {Name: "Watch", IsServerStream: true}}},
+	})
+	assertSeries(t, scrape(t, m),
+		`grpc_server_handled_total{grpc_code="OK",grpc_method="HealthCheck",grpc_service="common.v1.CommonService",grpc_type="unary"} 0`,
+		`grpc_server_handled_total{grpc_code="OK",grpc_method="Watch",grpc_service="common.v1.CommonService",grpc_type="server_stream"} 0`,
+	)
+
+	done := m.GRPCStarted("/common.v1.CommonService/HealthCheck", Unary)
+	assertSeries(t, scrape(t, m), `grpc_server_in_flight{grpc_method="HealthCheck",grpc_service="common.v1.CommonService"} 1`)
+	done(codes.Unavailable, time.Millisecond)
+	assertSeries(t, scrape(t, m),
+		`grpc_server_in_flight{grpc_method="HealthCheck",grpc_service="common.v1.CommonService"} 0`,
+		`grpc_server_handled_total{grpc_code="Unavailable",grpc_method="HealthCheck",grpc_service="common.v1.CommonService",grpc_type="unary"} 1`,
+		`grpc_server_handling_seconds_count{grpc_method="HealthCheck",grpc_service="common.v1.CommonService",grpc_type="unary"} 1`,
+	)
+}
+
+func TestStreamType(t *testing.T) {
+	tests := []struct {
+		client, server bool
+		want           string
+	}{
+		{want: Unary},
+		{client: true, want: ClientStream},
+		{server: true, want: ServerStream},
+		{client: true, server: true, want: BidiStream},
+	}
+	for _, tt := range tests {
+		if got := StreamType(tt.client, tt.server); got != tt.want {
+			t.Errorf("StreamType(%v, %v) = %q, want %q", tt.client, tt.server, got, tt.want)
+		}
+	}
+}
+
+func TestSplitMethodName(t *testing.T) {
+	tests := []struct {
+		fullMethod              string
+		wantService, wantMethod string
+	}{
+		{fullMethod: "/common.v1.CommonService/HealthCheck", wantService: "common.v1.CommonService", wantMethod: "HealthCheck"},
+		{fullMethod: "HealthCheck", wantService: "unknown", wantMethod: "HealthCheck"},
+	}
+	for _, tt := range tests {
+		service, method := splitMethodName(tt.fullMethod)
+		if service != tt.wantService || method != tt.wantMethod {
+			t.Errorf("splitMethodName(%q) = %q, %q, want %q, %q", tt.fullMethod, service, method, tt.wantService, tt.wantMethod)
+		}
+	}
+}
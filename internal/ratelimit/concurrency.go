@@ -0,0 +1,26 @@
+package ratelimit
+
+// ConcurrencyLimiter bounds the number of requests in flight
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+}
+
+// NewConcurrencyLimiter creates a limiter admitting at most max requests at once
+func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{slots: make(chan struct{}, max)}
+}
+
+// TryAcquire takes a slot without waiting, reporting whether one was free
+func (l *ConcurrencyLimiter) TryAcquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot taken by TryAcquire
+func (l *ConcurrencyLimiter) Release() {
+	<-l.slots
+}
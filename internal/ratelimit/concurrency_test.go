@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(2)
+	if !limiter.TryAcquire() || !limiter.TryAcquire() {
+		t.Fatal("TryAcquire() = false, want the first two slots")
+	}
+	if limiter.TryAcquire() {
+		t.Fatal("TryAcquire() = true past the limit")
+	}
+
+	limiter.Release()
+	if !limiter.TryAcquire() {
+		t.Fatal("TryAcquire() = false after a release")
+	}
+}
+
+func TestConcurrencyLimiterConcurrent(t *testing.T) {
+	const slots = 5
+	limiter := NewConcurrencyLimiter(slots)
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var admitted atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !limiter.TryAcquire() {
+				return
+			}
+			admitted.Add(1)
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			inFlight.Add(-1)
+			limiter.Release()
+		}()
+	}
+
+	close(release)
+	wg.Wait()
+	if peak.Load() > slots {
+		t.Errorf("peak in flight = %d, want at most %d", peak.Load(), slots)
+	}
+	if admitted.Load() == 0 {
+		t.Error("no request was admitted")
+	}
+	// Every slot is free again
+	for i := 0; i < slots; i++ {
+		if !limiter.TryAcquire() {
+			t.Fatalf("TryAcquire() = false for slot %d after every release", i)
+		}
+	}
+}
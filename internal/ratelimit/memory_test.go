@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreBurst(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("Take() = %+v, want allowed with %d remaining", result, i)
+		}
+	}
+
+	result, err := store.Take(context.Background(), "client", limit)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if result.Allowed {
+		t.Fatalf("Take() past the burst = %+v, want denied", result)
+	}
+	// An empty bucket refills one token per second
+	if result.RetryAfter <= 900*time.Millisecond || result.RetryAfter > time.Second {
+		t.Errorf("RetryAfter = %s, want about 1s", result.RetryAfter)
+	}
+
+	// Buckets are per key
+	if result, _ := store.Take(context.Background(), "other", limit); !result.Allowed {
+		t.Errorf("Take() of another key = %+v, want allowed", result)
+	}
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 100, Burst: 1}
+
+	if result, _ := store.Take(context.Background(), "client", limit); !result.Allowed {
+		t.Fatalf("first Take() = %+v, want allowed", result)
+	}
+	if result, _ := store.Take(context.Background(), "client", limit); result.Allowed {
+		t.Fatalf("second Take() = %+v, want denied", result)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	result, _ := store.Take(context.Background(), "client", limit)
+	if !result.Allowed {
+		t.Fatalf("Take() after refilling = %+v, want allowed", result)
+	}
+	// Refilling never exceeds the burst
+	if result.Remaining != 0 {
+		t.Errorf("Remaining = %d, want 0 with a burst of 1", result.Remaining)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	store.Take(context.Background(), "idle", limit)
+
+	store.mu.Lock()
+	store.buckets["idle"].last = time.Now().Add(-2 * sweepInterval)
+	store.lastSweep = time.Now().Add(-2 * sweepInterval)
+	store.mu.Unlock()
+
+	store.Take(context.Background(), "active", limit)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if _, ok := store.buckets["active"]; !ok {
+		t.Error("active bucket was swept")
+	}
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultScope is the bucket scope shared by routes and methods without an override
+const defaultScope = "default"
+
+// Limit is the token bucket of a client: Rate tokens are added per second up
+// to Burst, and every request takes one
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Unlimited reports whether requests are never limited
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// RetryAfter is how long until a token is available when not allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets of every client
+type Store interface {
+	// Take removes a token from the bucket of key
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter applies the configured limits to clients. Routes and methods with an
+// override get their own bucket per client, all others share the default bucket.
+type Limiter struct {
+	rules  atomic.Pointer[Rules]
+	store  Store
+	logger *logrus.Logger
+}
+
+// NewLimiter creates a limiter taking tokens from store
+func NewLimiter(rules *Rules, store Store, logger *logrus.Logger) *Limiter {
+	l := &Limiter{store: store, logger: logger}
+	l.rules.Store(rules)
+	return l
+}
+
+// SetRules replaces the rules applied to new requests, buckets keep their
+// tokens and refill at the new rate
+func (l *Limiter) SetRules(rules *Rules) {
+	l.rules.Store(rules)
+}
+
+// AllowMethod takes a token for a call of client to a full gRPC method name
+func (l *Limiter) AllowMethod(ctx context.Context, fullMethod, client string) Result {
+	limit, scope := l.rules.Load().ForMethod(fullMethod)
+	return l.take(ctx, client+"|"+scope, limit)
+}
+
+// AllowRoute takes a token for a request of client to an HTTP route template
+func (l *Limiter) AllowRoute(ctx context.Context, method, route, client string) Result {
+	limit, scope := l.rules.Load().ForRoute(method, route)
+	return l.take(ctx, client+"|"+scope, limit)
+}
+
+// take allows the request when the store fails, an unavailable store must not
+// take the whole service down
+func (l *Limiter) take(ctx context.Context, key string, limit Limit) Result {
+	if limit.Unlimited() {
+		return Result{Allowed: true, Remaining: limit.Burst}
+	}
+	result, err := l.store.Take(ctx, key, limit)
+	if err != nil {
+		logging.FromContext(ctx, l.logger).Warnf("Rate limit store unavailable, allowing request: %v", err)
+		return Result{Allowed: true}
+	}
+	return result
+}
+
+// Rules resolves the limit of gRPC methods and HTTP routes from config,
+// falling back to the default limit
+type Rules struct {
+	defaultLimit Limit
+	methods      map[string]Limit
+	services     map[string]Limit
+	routes       map[string]Limit
+}
+
+// NewRules creates the rules of cfg
+func NewRules(cfg *config.RateLimitConfig) (*Rules, error) {
+	r := &Rules{
+		defaultLimit: Limit{Rate: cfg.Rate, Burst: cfg.Burst},
+		methods:      map[string]Limit{},
+		services:     map[string]Limit{},
+		routes:       map[string]Limit{},
+	}
+
+	for i, override := range cfg.Overrides {
+		limit := Limit{Rate: override.Rate, Burst: override.Burst}
+		switch {
+		case override.Route != "":
+			method, path, ok := strings.Cut(override.Route, " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid rate limit override %d: route must be \"METHOD /path\"", i)
+			}
+			r.routes[routeKey(method, path)] = limit
+		case strings.HasSuffix(override.Method, "/*"):
+			r.services[strings.TrimSuffix(strings.TrimPrefix(override.Method, "/"), "/*")] = limit
+		default:
+			r.methods[override.Method] = limit
+		}
+	}
+
+	return r, nil
+}
+
+// ForMethod returns the limit of a full gRPC method name along with the scope
+// of its bucket
+func (r *Rules) ForMethod(fullMethod string) (Limit, string) {
+	if limit, ok := r.methods[fullMethod]; ok {
+		return limit, fullMethod
+	}
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if limit, ok := r.services[service]; ok {
+		return limit, service
+	}
+	return r.defaultLimit, defaultScope
+}
+
+// ForRoute returns the limit of an HTTP method and route template along with
+// the scope of its bucket
+func (r *Rules) ForRoute(method, route string) (Limit, string) {
+	key := routeKey(method, route)
+	if limit, ok := r.routes[key]; ok {
+		return limit, key
+	}
+	return r.defaultLimit, defaultScope
+}
+
+func routeKey(method, route string) string {
+	return strings.ToUpper(method) + " " + route
+}
+
+// RetryAfterSeconds rounds a retry delay up to whole seconds, as used by the
+// Retry-After header
+func RetryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestRules(t *testing.T) *Rules {
+	t.Helper()
+	rules, err := NewRules(&config.RateLimitConfig{
+		Rate:  10,
+		Burst: 20,
+		Overrides: []config.RateLimitOverride{
+			{Method: "/svc.v1.Svc/*", Rate: 5, Burst: 5},
+			{Method: "/svc.v1.Svc/Upload", Rate: 1, Burst: 1},
+			{Method: "/grpc.health.v1.Health/Check", Rate: 0},
+			{Route: "post /v1/orders", Rate: 2, Burst: 4},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+	return rules
+}
+
+func TestRulesForMethod(t *testing.T) {
+	rules := newTestRules(t)
+	tests := []struct {
+		method    string
+		wantLimit Limit
+		wantScope string
+	}{
+		{method: "/svc.v1.Svc/Upload", wantLimit: Limit{Rate: 1, Burst: 1}, wantScope: "/svc.v1.Svc/Upload"},
+		{method: "/svc.v1.Svc/Get", wantLimit: Limit{Rate: 5, Burst: 5}, wantScope: "svc.v1.Svc"},
+		{method: "/grpc.health.v1.Health/Check", wantLimit: Limit{}, wantScope: "/grpc.health.v1.Health/Check"},
+		{method: "/svc.v1.Other/Get", wantLimit: Limit{Rate: 10, Burst: 20}, wantScope: defaultScope},
+	}
+	for _, tt := range tests {
+		limit, scope := rules.ForMethod(tt.method)
+		if limit != tt.wantLimit || scope != tt.wantScope {
+			t.Errorf("ForMethod(%q) = %+v, %q, want %+v, %q", tt.method, limit, scope, tt.wantLimit, tt.wantScope)
+		}
+	}
+}
+
+func TestRulesForRoute(t *testing.T) {
+	rules := newTestRules(t)
+	if limit, scope := rules.ForRoute("POST", "/v1/orders"); limit != (Limit{Rate: 2, Burst: 4}) || scope != "POST /v1/orders" {
+		t.Errorf("ForRoute(POST) = %+v, %q, want the override", limit, scope)
+	}
+	if limit, scope := rules.ForRoute("GET", "/v1/orders"); limit != (Limit{Rate: 10, Burst: 20}) || scope != defaultScope {
+		t.Errorf("ForRoute(GET) = %+v, %q, want the default", limit, scope)
+	}
+}
+
+func TestNewRulesRejectsInvalidRoute(t *testing.T) {
+	_, err := NewRules(&config.RateLimitConfig{Overrides: []config.RateLimitOverride{{Route: "/v1/orders", Rate: 1, Burst: 1}}})
+	if err == nil {
+		t.Error("NewRules() error = nil, want an invalid route error")
+	}
+}
+
+// failingStore fails every Take
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func newTestLimiter(t *testing.T, store Store) *Limiter {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewLimiter(newTestRules(t), store, logger)
+}
+
+func TestLimiterBuckets(t *testing.T) {
+	limiter := newTestLimiter(t, NewMemoryStore())
+	ctx := context.Background()
+
+	if !limiter.AllowMethod(ctx, "/svc.v1.Svc/Upload", "a").Allowed {
+		t.Fatal("first upload denied")
+	}
+	if limiter.AllowMethod(ctx, "/svc.v1.Svc/Upload", "a").Allowed {
+		t.Fatal("second upload allowed past its burst of 1")
+	}
+	// Another client and another scope have their own buckets
+	if !limiter.AllowMethod(ctx, "/svc.v1.Svc/Upload", "b").Allowed {
+		t.Error("upload of another client denied")
+	}
+	if !limiter.AllowMethod(ctx, "/svc.v1.Svc/Get", "a").Allowed {
+		t.Error("call to another scope denied")
+	}
+
+	// Unlimited methods never take a token
+	for i := 0; i < 100; i++ {
+		if !limiter.AllowMethod(ctx, "/grpc.health.v1.Health/Check", "a").Allowed {
+			t.Fatal("unlimited method denied")
+		}
+	}
+
+	// Routes and methods without an override share the default bucket
+	for i := 0; i < 20; i++ {
+		limiter.AllowRoute(ctx, "GET", "/v1/other", "c")
+	}
+	if limiter.AllowMethod(ctx, "/svc.v1.Other/Get", "c").Allowed {
+		t.Error("default bucket not shared by routes and methods")
+	}
+}
+
+func TestLimiterSetRules(t *testing.T) {
+	limiter := newTestLimiter(t, NewMemoryStore())
+	ctx := context.Background()
+	limiter.AllowMethod(ctx, "/svc.v1.Svc/Upload", "a")
+
+	rules, err := NewRules(&config.RateLimitConfig{Rate: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.SetRules(rules)
+	if !limiter.AllowMethod(ctx, "/svc.v1.Svc/Upload", "a").Allowed {
+		t.Error("call denied after limits were removed")
+	}
+}
+
+func TestLimiterAllowsWhenStoreFails(t *testing.T) {
+	limiter := newTestLimiter(t, failingStore{})
+	if !limiter.AllowMethod(context.Background(), "/svc.v1.Svc/Get", "a").Allowed {
+		t.Error("call denied when the store is unavailable")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	for d, want := range map[time.Duration]int{
+		0:                       1,
+		100 * time.Millisecond:  1,
+		time.Second:             1,
+		1001 * time.Millisecond: 2,
+		90 * time.Second:        90,
+	} {
+		if got := RetryAfterSeconds(d); got != want {
+			t.Errorf("RetryAfterSeconds(%s) = %d, want %d", d, got, want)
+		}
+	}
+}
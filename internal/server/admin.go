@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/authz"
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/admin"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// adminServer serves the admin endpoints and metrics on a dedicated port so
+// they are not exposed through the public listener. Routes other than the
+// public ones are authenticated and authorized like those of the HTTP server,
+// which it shares the timeouts, access logs and panic recovery of.
+type adminServer struct {
+	server *http.Server
+	access *routeAccess
+	listen listenFunc
+	logger *logrus.Logger
+	ready  atomic.Bool
+}
+
+type adminServerParams struct {
+	config *config.AdminConfig
+	// http holds the timeouts, the same as the HTTP server's
+	http          *config.HTTPConfig
+	verboseErrors bool
+	handlers      common.HttpHandler
+	logger        *logrus.Logger
+	// auth requires a bearer token on non-public routes, nil disables it
+	auth *auth.Authenticator
+	// authz enforces route policies on non-public routes, nil disables it
+	authz *authz.Engine
+}
+
+// unprotectedAdmin reports whether the admin endpoints accept remote
+// connections without authentication, served by the HTTP server or by an
+// admin server not bound to loopback
+func unprotectedAdmin(cfg *config.Config) bool {
+	admin := &cfg.Server.Admin
+	return !cfg.Auth.Enabled && (admin.Port == 0 || !isLoopback(admin.Host))
+}
+
+// restrictAdmin leaves out the admin routes changing the process or exposing
+// its internals, keeping the metrics and the log level
+func restrictAdmin(params *admin.AdminHandlerParams) {
+	params.ReadOnly = true
+	params.Pprof = false
+	params.Config = nil
+}
+
+// newAdminServer creates an admin server on the configured host and port
+func newAdminServer(params adminServerParams) *adminServer {
+	router := mux.NewRouter()
+	params.handlers.RegisterRoutes(router)
+
+	access := &routeAccess{
+		router:        router,
+		logger:        params.logger,
+		auth:          params.auth,
+		authz:         params.authz,
+		publicRoutes:  map[string]bool{},
+		gatewayRoutes: map[string]bool{},
+	}
+	for _, route := range common.PublicRoutes(params.handlers) {
+		access.publicRoutes[route] = true
+	}
+
+	handler := panicRecoveryMiddleware(params.logger, params.verboseErrors, access.protect(router))
+	handler = requestLoggingMiddleware(params.logger, handler)
+	handler = routeMiddleware(router, handler)
+
+	return &adminServer{
+		server: &http.Server{
+			Addr:              net.JoinHostPort(params.config.Host, strconv.Itoa(params.config.Port)),
+			Handler:           handler,
+			ReadHeaderTimeout: params.http.ReadTimeout,
+			ReadTimeout:       params.http.ReadTimeout,
+			WriteTimeout:      params.http.WriteTimeout,
+			IdleTimeout:       params.http.IdleTimeout,
+		},
+		access: access,
+		listen: tcpListener(params.config.Host, params.config.Port),
+		logger: params.logger,
+	}
+}
+
+// Name returns the component name
+func (s *adminServer) Name() string {
+	return "admin server"
+}
+
+// Start starts the admin server and blocks until it is stopped
+func (s *adminServer) Start(ctx context.Context) error {
+	lis, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s.logger.Infof("Starting admin server on %s", lis.Addr())
+	s.ready.Store(true)
+	defer s.ready.Store(false)
+
+	if err := s.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+
+	return nil
+}
+
+// Stop gracefully shuts down the admin server
+func (s *adminServer) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// Ready reports whether the admin server is accepting connections
+func (s *adminServer) Ready() bool {
+	return s.ready.Load()
+}
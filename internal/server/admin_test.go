@@ -0,0 +1,105 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/admin"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUnprotectedAdmin(t *testing.T) {
+	tests := []struct {
+		name string
+		auth bool
+		host string
+		port int
+		want bool
+	}{
+		{name: "loopback", host: "127.0.0.1", port: 8087},
+		{name: "ipv6 loopback", host: "::1", port: 8087},
+		{name: "localhost", host: "localhost", port: 8087},
+		{name: "every interface", host: "0.0.0.0", port: 8087, want: true},
+		{name: "empty host", host: "", port: 8087, want: true},
+		{name: "remote address", host: "10.0.0.5", port: 8087, want: true},
+		// Without a port the HTTP server, bound to every interface, serves them
+		{name: "shared with the HTTP server", host: "127.0.0.1", want: true},
+		{name: "authenticated", auth: true, host: "0.0.0.0", port: 8087},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Auth.Enabled = tt.auth
+			cfg.Server.Admin = config.AdminConfig{Host: tt.host, Port: tt.port}
+			if got := unprotectedAdmin(cfg); got != tt.want {
+				t.Errorf("unprotectedAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// panicHandler adds an admin route panicking
+type panicHandler struct{ common.HttpHandler }
+
+func (h panicHandler) RegisterRoutes(router *mux.Router) {
+	h.HttpHandler.RegisterRoutes(router)
+	router.HandleFunc("/admin/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+}
+
+func TestAdminServer(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	params := admin.AdminHandlerParams{
+		Logger:      logger,
+		Metrics:     http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "metrics") }),
+		MetricsPath: "/metrics",
+		Pprof:       true,
+		Config:      func() map[string]any { return map[string]any{} },
+	}
+	restrictAdmin(&params)
+
+	server := newAdminServer(adminServerParams{
+		config:   &config.AdminConfig{Host: "127.0.0.1", Port: 8087},
+		http:     &config.HTTPConfig{ReadTimeout: time.Second, WriteTimeout: 2 * time.Second, IdleTimeout: 3 * time.Second},
+		handlers: panicHandler{admin.NewAdminHandler(params)},
+		logger:   logger,
+	})
+	s := server.server
+	if s.Addr != "127.0.0.1:8087" || s.ReadHeaderTimeout != time.Second || s.WriteTimeout != 2*time.Second || s.IdleTimeout != 3*time.Second {
+		t.Errorf("server = %s with timeouts %v %v %v, want the admin address and the HTTP timeouts", s.Addr, s.ReadHeaderTimeout, s.WriteTimeout, s.IdleTimeout)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: http.MethodGet, path: "/metrics", want: http.StatusOK},
+		{method: http.MethodGet, path: "/admin/log-level", want: http.StatusOK},
+		// Restricted routes are not registered
+		{method: http.MethodPut, path: "/admin/log-level", want: http.StatusMethodNotAllowed},
+		{method: http.MethodGet, path: "/debug/config", want: http.StatusNotFound},
+		{method: http.MethodGet, path: "/debug/pprof/heap", want: http.StatusNotFound},
+		{method: http.MethodGet, path: "/admin/panic", want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.Handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"level":"debug"}`)))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Header().Get(logging.RequestIDHeader) == "" {
+				t.Errorf("response has no request ID, want the access logging middleware")
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultStartTimeout bounds how long a component may take to become ready
+	defaultStartTimeout = 30 * time.Second
+	// defaultShutdownTimeout bounds how long a component may take to stop
+	defaultShutdownTimeout = 30 * time.Second
+	// readyPollInterval is how often a starting component is polled for readiness
+	readyPollInterval = 10 * time.Millisecond
+)
+
+// Component is a long-running unit managed by the Server, such as a listener,
+// a background worker or a queue consumer
+type Component interface {
+	// Name returns the name used in logs and errors
+	Name() string
+	// Start runs the component and blocks until it stops or fails
+	Start(ctx context.Context) error
+	// Stop gracefully stops the component within the deadline of ctx. It is
+	// also called, once, on a component that never started when the server
+	// fails to start, to release what it holds such as connection pools.
+	Stop(ctx context.Context) error
+	// Ready reports whether the component has started and is able to serve
+	Ready() bool
+}
+
+// ComponentOption configures how the Server manages a registered component
+type ComponentOption func(*managedComponent)
+
+// WithStartTimeout sets how long the component may take to become ready
+func WithStartTimeout(timeout time.Duration) ComponentOption {
+	return func(mc *managedComponent) {
+		mc.startTimeout = timeout
+	}
+}
+
+// WithShutdownTimeout sets how long the component may take to stop
+func WithShutdownTimeout(timeout time.Duration) ComponentOption {
+	return func(mc *managedComponent) {
+		mc.shutdownTimeout = timeout
+	}
+}
+
+// managedComponent tracks the runtime state of a registered component
+type managedComponent struct {
+	component       Component
+	startTimeout    time.Duration
+	shutdownTimeout time.Duration
+
+	started bool
+	// stopped is set once Stop was called, a component is stopped at most once
+	stopped bool
+	done    chan struct{}
+	err     error
+}
+
+func newManagedComponent(component Component, opts ...ComponentOption) *managedComponent {
+	mc := &managedComponent{
+		component:       component,
+		startTimeout:    defaultStartTimeout,
+		shutdownTimeout: defaultShutdownTimeout,
+		done:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(mc)
+	}
+	return mc
+}
+
+// run starts the component and records its exit, converting panics into errors
+func (mc *managedComponent) run(ctx context.Context, exited chan<- *managedComponent) {
+	defer func() {
+		if r := recover(); r != nil {
+			mc.err = fmt.Errorf("panic: %v\nStack trace:\n%s", r, debug.Stack())
+		}
+		close(mc.done)
+		exited <- mc
+	}()
+	mc.err = mc.component.Start(ctx)
+}
+
+// waitReady blocks until the component reports ready, exits or times out
+func (mc *managedComponent) waitReady(ctx context.Context) error {
+	timer := time.NewTimer(mc.startTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if mc.component.Ready() {
+			return nil
+		}
+		select {
+		case <-mc.done:
+			if mc.err != nil {
+				return mc.err
+			}
+			return errors.New("exited before becoming ready")
+		case <-timer.C:
+			return fmt.Errorf("not ready after %s", mc.startTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop gracefully stops the component and waits for Start to return
+func (mc *managedComponent) stop(ctx context.Context) error {
+	stopCtx, cancel := context.WithTimeout(ctx, mc.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := mc.component.Stop(stopCtx); err != nil {
+		errs = append(errs, err)
+	}
+
+	select {
+	case <-mc.done:
+		if mc.err != nil {
+			errs = append(errs, mc.err)
+		}
+	case <-stopCtx.Done():
+		errs = append(errs, fmt.Errorf("did not stop within %s", mc.shutdownTimeout))
+	}
+
+	return errors.Join(errs...)
+}
+
+// lifecycle starts components in registration order and stops them in reverse
+type lifecycle struct {
+	mu         sync.Mutex
+	components []*managedComponent
+	exited     chan *managedComponent
+	cancel     context.CancelFunc
+}
+
+// add registers a component, it must be called before start
+func (l *lifecycle) add(component Component, opts ...ComponentOption) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.components = append(l.components, newManagedComponent(component, opts...))
+}
+
+// start runs every component in order, waiting for each to become ready before
+// starting the next one. On failure the already started components are stopped.
+func (l *lifecycle) start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Components outlive the caller's context, they are only stopped through stop
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	l.cancel = cancel
+	l.exited = make(chan *managedComponent, len(l.components))
+
+	for _, mc := range l.components {
+		mc.started = true
+		go mc.run(runCtx, l.exited)
+
+		if err := mc.waitReady(ctx); err != nil {
+			startErr := fmt.Errorf("failed to start %s: %w", mc.component.Name(), err)
+			stopCtx := context.WithoutCancel(ctx)
+			return errors.Join(startErr, l.stopLocked(stopCtx), l.releaseLocked(stopCtx))
+		}
+	}
+
+	return nil
+}
+
+// wait blocks until a component exits or ctx is done and returns the exit error
+// of the component, if any
+func (l *lifecycle) wait(ctx context.Context) error {
+	select {
+	case mc := <-l.exited:
+		if mc.err != nil {
+			return fmt.Errorf("%s exited: %w", mc.component.Name(), mc.err)
+		}
+		return fmt.Errorf("%s exited unexpectedly", mc.component.Name())
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// stop stops every started component in reverse order and aggregates errors
+func (l *lifecycle) stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stopLocked(ctx)
+}
+
+func (l *lifecycle) stopLocked(ctx context.Context) error {
+	var errs []error
+	for i := len(l.components) - 1; i >= 0; i-- {
+		mc := l.components[i]
+		if !mc.started || mc.stopped {
+			continue
+		}
+		mc.stopped = true
+		if err := mc.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", mc.component.Name(), err))
+		}
+	}
+
+	if l.cancel != nil {
+		l.cancel()
+	}
+
+	return errors.Join(errs...)
+}
+
+// release calls Stop on the components that were never started, in reverse
+// order, for a server failing before its components start
+func (l *lifecycle) release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.releaseLocked(ctx)
+}
+
+func (l *lifecycle) releaseLocked(ctx context.Context) error {
+	var errs []error
+	for i := len(l.components) - 1; i >= 0; i-- {
+		mc := l.components[i]
+		if mc.started || mc.stopped {
+			continue
+		}
+		mc.stopped = true
+		stopCtx, cancel := context.WithTimeout(ctx, mc.shutdownTimeout)
+		err := mc.component.Stop(stopCtx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to release %s: %w", mc.component.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
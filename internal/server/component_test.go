@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// events records the calls made to test components in order
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.list...)
+}
+
+// testComponent blocks in Start until stopped, becoming ready unless it fails
+type testComponent struct {
+	name     string
+	events   *events
+	startErr error
+	// neverReady keeps the component starting until it is stopped
+	neverReady bool
+	stopErr    error
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	ready    atomic.Bool
+}
+
+func newTestComponent(name string, e *events) *testComponent {
+	return &testComponent{name: name, events: e, stop: make(chan struct{})}
+}
+
+func (c *testComponent) Name() string {
+	return c.name
+}
+
+func (c *testComponent) Start(ctx context.Context) error {
+	c.events.add("start " + c.name)
+	if c.startErr != nil {
+		return c.startErr
+	}
+	if !c.neverReady {
+		c.ready.Store(true)
+	}
+	<-c.stop
+	return nil
+}
+
+func (c *testComponent) Stop(ctx context.Context) error {
+	c.events.add("stop " + c.name)
+	c.ready.Store(false)
+	c.stopOnce.Do(func() { close(c.stop) })
+	return c.stopErr
+}
+
+func (c *testComponent) Ready() bool {
+	return c.ready.Load()
+}
+
+func TestLifecycleOrder(t *testing.T) {
+	e := &events{}
+	var l lifecycle
+	for _, name := range []string{"a", "b", "c"} {
+		l.add(newTestComponent(name, e))
+	}
+
+	if err := l.start(context.Background()); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+	if err := l.stop(context.Background()); err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+	// A second stop does not stop the components again
+	if err := l.stop(context.Background()); err != nil {
+		t.Fatalf("second stop() error = %v", err)
+	}
+
+	want := []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"}
+	if got := e.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestLifecycleStartFailureRollsBack(t *testing.T) {
+	e := &events{}
+	var l lifecycle
+	failing := newTestComponent("b", e)
+	failing.startErr = errors.New("boom")
+	l.add(newTestComponent("a", e))
+	l.add(failing)
+	l.add(newTestComponent("c", e))
+
+	err := l.start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to start b: boom") {
+		t.Fatalf("start() error = %v, want the failure of b", err)
+	}
+
+	// a is stopped after running, c never started and is only released
+	want := []string{"start a", "start b", "stop b", "stop a", "stop c"}
+	if got := e.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestLifecycleStartTimeout(t *testing.T) {
+	e := &events{}
+	var l lifecycle
+	slow := newTestComponent("slow", e)
+	slow.neverReady = true
+	l.add(newTestComponent("a", e))
+	l.add(slow, WithStartTimeout(20*time.Millisecond))
+
+	err := l.start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to start slow: not ready after 20ms") {
+		t.Fatalf("start() error = %v, want a readiness timeout", err)
+	}
+	want := []string{"start a", "start slow", "stop slow", "stop a"}
+	if got := e.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestLifecycleRelease(t *testing.T) {
+	e := &events{}
+	var l lifecycle
+	failing := newTestComponent("b", e)
+	failing.stopErr = errors.New("close failed")
+	l.add(newTestComponent("a", e))
+	l.add(failing)
+
+	err := l.release(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to release b: close failed") {
+		t.Fatalf("release() error = %v, want the failure of b", err)
+	}
+	// Released components are not stopped again
+	if err := l.stop(context.Background()); err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+	if err := l.release(context.Background()); err != nil {
+		t.Fatalf("second release() error = %v", err)
+	}
+
+	want := []string{"stop b", "stop a"}
+	if got := e.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestLifecycleWait(t *testing.T) {
+	e := &events{}
+	var l lifecycle
+	c := newTestComponent("worker", e)
+	l.add(c)
+	if err := l.start(context.Background()); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+	defer l.stop(context.Background())
+
+	// A component returning on its own is reported as an unexpected exit
+	c.stopOnce.Do(func() { close(c.stop) })
+	err := l.wait(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "worker exited unexpectedly") {
+		t.Errorf("wait() error = %v, want an unexpected exit", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx); err != nil {
+		t.Errorf("wait() with a done context error = %v, want nil", err)
+	}
+}
+
+func TestLifecycleRecoversPanics(t *testing.T) {
+	var l lifecycle
+	l.add(panicComponent{})
+
+	err := l.start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "panic: start failed") {
+		t.Fatalf("start() error = %v, want the recovered panic", err)
+	}
+}
+
+// panicComponent panics on start
+type panicComponent struct{}
+
+func (panicComponent) Name() string                    { return "panicking" }
+func (panicComponent) Start(ctx context.Context) error { panic("start failed") }
+func (panicComponent) Stop(ctx context.Context) error  { return nil }
+func (panicComponent) Ready() bool                     { return false }
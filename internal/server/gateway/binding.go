@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// metadataHeaderPrefix prefixes response headers carrying gRPC metadata
+const metadataHeaderPrefix = "Grpc-Metadata-"
+
+// hopHeaders are connection level headers that are not forwarded as metadata
+var hopHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Keep-Alive":        true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// incomingMetadata converts request headers into incoming gRPC metadata
+func incomingMetadata(r *http.Request) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range r.Header {
+		if hopHeaders[key] {
+			continue
+		}
+		md.Append(strings.ToLower(key), values...)
+	}
+	return md
+}
+
+// writeMetadata writes header metadata set by the handler as response headers
+func writeMetadata(w http.ResponseWriter, md metadata.MD) {
+	for key, values := range md {
+		for _, value := range values {
+			w.Header().Add(metadataHeaderPrefix+textproto.CanonicalMIMEHeaderKey(key), value)
+		}
+	}
+}
+
+// writeTrailers writes trailer metadata set by the handler as HTTP trailers
+func writeTrailers(w http.ResponseWriter, md metadata.MD) {
+	for key, values := range md {
+		for _, value := range values {
+			w.Header().Add(http.TrailerPrefix+"Grpc-Trailer-"+textproto.CanonicalMIMEHeaderKey(key), value)
+		}
+	}
+}
+
+// serverTransportStream lets handlers set headers and trailers with
+// grpc.SetHeader and grpc.SetTrailer when called through the gateway
+type serverTransportStream struct {
+	method  string
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *serverTransportStream) Method() string {
+	return s.method
+}
+
+func (s *serverTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *serverTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *serverTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// unmarshalBody decodes the request body into msg, or into the field named by body
+func unmarshalBody(opts protojson.UnmarshalOptions, data []byte, msg protoreflect.Message, body string) error {
+	if body == "*" {
+		if err := opts.Unmarshal(data, msg.Interface()); err != nil {
+			return fmt.Errorf("invalid body: %w", err)
+		}
+		return nil
+	}
+
+	parent, fd, err := resolveField(msg, body)
+	if err != nil {
+		return err
+	}
+	if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+		return fmt.Errorf("body field %s must be a message", body)
+	}
+	if err := opts.Unmarshal(data, parent.Mutable(fd).Message().Interface()); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+	return nil
+}
+
+// marshalField encodes the field named by path of msg
+func marshalField(opts protojson.MarshalOptions, msg protoreflect.Message, path string) ([]byte, error) {
+	parent, fd, err := resolveField(msg, path)
+	if err != nil {
+		return nil, err
+	}
+	if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+		return nil, fmt.Errorf("response body field %s must be a message", path)
+	}
+	return opts.Marshal(parent.Get(fd).Message().Interface())
+}
+
+// setField parses value into the scalar field at the dotted path of msg,
+// appending to it when the field is repeated
+func setField(msg protoreflect.Message, path string, value string) error {
+	parent, fd, err := resolveField(msg, path)
+	if err != nil {
+		return err
+	}
+	if fd.IsMap() {
+		return fmt.Errorf("map field %s cannot be bound from a parameter", path)
+	}
+
+	v, err := parseScalar(fd, value)
+	if err != nil {
+		return err
+	}
+
+	if fd.IsList() {
+		parent.Mutable(fd).List().Append(v)
+		return nil
+	}
+	parent.Set(fd, v)
+	return nil
+}
+
+// resolveField walks the dotted path, allocating intermediate messages, and
+// returns the message holding the final field along with its descriptor
+func resolveField(msg protoreflect.Message, path string) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fields := msg.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return nil, nil, fmt.Errorf("unknown field %s", path)
+		}
+		if i == len(names)-1 {
+			return msg, fd, nil
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return nil, nil, fmt.Errorf("field %s is not a message", name)
+		}
+		msg = msg.Mutable(fd).Message()
+	}
+	return nil, nil, fmt.Errorf("empty field path")
+}
+
+// parseScalar converts a string parameter into a value of the field's kind
+func parseScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			b, err = base64.URLEncoding.DecodeString(value)
+		}
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown enum value %q", value)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("field %s of kind %s cannot be bound from a parameter", fd.Name(), fd.Kind())
+}
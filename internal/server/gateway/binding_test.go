@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/pkg/proto/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// bindRequest routes the request through the template of rule and binds it
+// into msg
+func bindRequest(t *testing.T, rule *annotations.HttpRule, r *http.Request, msg proto.Message) error {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	g := NewGateway(GatewayParams{Logger: logger})
+
+	method, pattern := ruleMethodAndPattern(rule)
+	tmpl, err := parseTemplate(pattern)
+	if err != nil {
+		t.Fatalf("parseTemplate() error = %v", err)
+	}
+
+	var bindErr error
+	matched := false
+	router := mux.NewRouter()
+	router.HandleFunc(tmpl.route, func(w http.ResponseWriter, r *http.Request) {
+		matched = true
+		bindErr = g.bind(r, msg.ProtoReflect(), rule, tmpl)
+	}).Methods(method)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	if !matched {
+		t.Fatalf("%s %s does not match %s", r.Method, r.URL, pattern)
+	}
+	return bindErr
+}
+
+func TestBindPathAndQuery(t *testing.T) {
+	rule := &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name=fields/*}/{number}"}}
+	r := httptest.NewRequest(http.MethodGet, "/v1/fields/id/7?label=LABEL_REPEATED&jsonName=ident&options.packed=true&options.deprecated=1", nil)
+
+	got := &descriptorpb.FieldDescriptorProto{}
+	if err := bindRequest(t, rule, r, got); err != nil {
+		t.Fatalf("bind() error = %v", err)
+	}
+	want := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("fields/id"),
+		Number:   proto.Int32(7),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		JsonName: proto.String("ident"),
+		Options:  &descriptorpb.FieldOptions{Packed: proto.Bool(true), Deprecated: proto.Bool(true)},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("bound %v, want %v", got, want)
+	}
+}
+
+func TestBindRepeatedQuery(t *testing.T) {
+	rule := &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/policies"}}
+	r := httptest.NewRequest(http.MethodGet, "/v1/policies?roles=admin&roles=ops&expr=true", nil)
+
+	got := &common.Policy{}
+	if err := bindRequest(t, rule, r, got); err != nil {
+		t.Fatalf("bind() error = %v", err)
+	}
+	want := &common.Policy{Roles: []string{"admin", "ops"}, Expr: "true"}
+	if !proto.Equal(got, want) {
+		t.Errorf("bound %v, want %v", got, want)
+	}
+}
+
+func TestBindBody(t *testing.T) {
+	t.Run("whole body", func(t *testing.T) {
+		rule := &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/fields/{number}"}, Body: "*"}
+		r := httptest.NewRequest(http.MethodPost, "/v1/fields/3?name=ignored", strings.NewReader(`{"name":"id","number":1,"unknown":true}`))
+
+		got := &descriptorpb.FieldDescriptorProto{}
+		if err := bindRequest(t, rule, r, got); err != nil {
+			t.Fatalf("bind() error = %v", err)
+		}
+		// Path parameters take precedence over the body, query parameters are
+		// ignored and unknown fields discarded
+		want := &descriptorpb.FieldDescriptorProto{Name: proto.String("id"), Number: proto.Int32(3)}
+		if !proto.Equal(got, want) {
+			t.Errorf("bound %v, want %v", got, want)
+		}
+	})
+
+	t.Run("body field", func(t *testing.T) {
+		rule := &annotations.HttpRule{Pattern: &annotations.HttpRule_Patch{Patch: "/v1/fields/{name}"}, Body: "options"}
+		r := httptest.NewRequest(http.MethodPatch, "/v1/fields/id?number=2&options.packed=false", strings.NewReader(`{"packed":true}`))
+
+		got := &descriptorpb.FieldDescriptorProto{}
+		if err := bindRequest(t, rule, r, got); err != nil {
+			t.Fatalf("bind() error = %v", err)
+		}
+		// Query parameters bind fields outside the body field only
+		want := &descriptorpb.FieldDescriptorProto{
+			Name:    proto.String("id"),
+			Number:  proto.Int32(2),
+			Options: &descriptorpb.FieldOptions{Packed: proto.Bool(true)},
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("bound %v, want %v", got, want)
+		}
+	})
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    *annotations.HttpRule
+		method  string
+		target  string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "path parameter kind",
+			rule:    &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/fields/{number}"}},
+			target:  "/v1/fields/one",
+			wantErr: "invalid path parameter number",
+		},
+		{
+			name:    "unknown query parameter",
+			rule:    &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/fields"}},
+			target:  "/v1/fields?missing=1",
+			wantErr: "invalid query parameter missing: unknown field missing",
+		},
+		{
+			name:    "unknown enum value",
+			rule:    &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/fields"}},
+			target:  "/v1/fields?label=LABEL_UNKNOWN",
+			wantErr: `unknown enum value "LABEL_UNKNOWN"`,
+		},
+		{
+			name:    "message parameter",
+			rule:    &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/fields"}},
+			target:  "/v1/fields?options=1",
+			wantErr: "field options of kind message cannot be bound",
+		},
+		{
+			name:    "path through a scalar",
+			rule:    &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/fields"}},
+			target:  "/v1/fields?name.first=1",
+			wantErr: "field name is not a message",
+		},
+		{
+			name:    "invalid body",
+			rule:    &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/fields"}, Body: "*"},
+			method:  http.MethodPost,
+			target:  "/v1/fields",
+			body:    `{"number":"x"}`,
+			wantErr: "invalid body",
+		},
+		{
+			name:    "scalar body field",
+			rule:    &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/fields"}, Body: "name"},
+			method:  http.MethodPost,
+			target:  "/v1/fields",
+			body:    `"id"`,
+			wantErr: "body field name must be a message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tt.target, strings.NewReader(tt.body))
+			err := bindRequest(t, tt.rule, r, &descriptorpb.FieldDescriptorProto{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("bind() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseScalar(t *testing.T) {
+	fields := (&descriptorpb.UninterpretedOption{}).ProtoReflect().Descriptor().Fields()
+	tests := []struct {
+		field string
+		value string
+		want  any
+	}{
+		{field: "identifier_value", value: "x", want: "x"},
+		{field: "positive_int_value", value: "18446744073709551615", want: uint64(18446744073709551615)},
+		{field: "negative_int_value", value: "-5", want: int64(-5)},
+		{field: "double_value", value: "1.5", want: 1.5},
+		// Bytes accept standard and URL-safe base64
+		{field: "string_value", value: "aGk+Pz8=", want: "hi>??"},
+		{field: "string_value", value: "aGk-Pz8=", want: "hi>??"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field+"="+tt.value, func(t *testing.T) {
+			v, err := parseScalar(fields.ByName(protoreflect.Name(tt.field)), tt.value)
+			if err != nil {
+				t.Fatalf("parseScalar() error = %v", err)
+			}
+			got := v.Interface()
+			if b, ok := got.([]byte); ok {
+				got = string(b)
+			}
+			if got != tt.want {
+				t.Errorf("parseScalar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseScalar(fields.ByName("positive_int_value"), "-1"); err == nil {
+		t.Errorf("parseScalar() of a negative unsigned value error = nil")
+	}
+}
@@ -0,0 +1,307 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Gateway transcodes HTTP/JSON requests into calls on gRPC services whose
+// methods carry google.api.http annotations. It implements grpc.ServiceRegistrar
+// so the same RegisterServices call used for the gRPC server can populate it.
+type Gateway struct {
+	logger       *logrus.Logger
+	interceptor  grpc.UnaryServerInterceptor
+	services     []service
+	routes       []string
+	bindings     []Binding
+	marshaler    protojson.MarshalOptions
+	unmarshaler  protojson.UnmarshalOptions
+	maxBodyBytes int64
+}
+
+type GatewayParams struct {
+	Logger *logrus.Logger
+	// Interceptor is applied to every transcoded call, it should be the same
+	// chain the gRPC server uses so both transports behave identically
+	Interceptor grpc.UnaryServerInterceptor
+	// MaxBodyBytes bounds the request bodies, larger ones are rejected with
+	// 413 Request Entity Too Large. Zero removes the limit.
+	MaxBodyBytes int64
+}
+
+// Binding is an HTTP route transcoded to a gRPC method
+type Binding struct {
+	// HTTPMethod and Path identify the route, Path being its template
+	HTTPMethod string
+	Path       string
+	// FullMethod is the gRPC method the route calls
+	FullMethod string
+}
+
+// service is a registered gRPC service implementation
+type service struct {
+	desc *grpc.ServiceDesc
+	impl any
+}
+
+// NewGateway creates a new gateway. Responses keep the proto field names,
+// matching the snake_case JSON of the plain HTTP handlers.
+func NewGateway(params GatewayParams) *Gateway {
+	return &Gateway{
+		logger:       params.Logger,
+		interceptor:  params.Interceptor,
+		marshaler:    protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+		unmarshaler:  protojson.UnmarshalOptions{DiscardUnknown: true},
+		maxBodyBytes: params.MaxBodyBytes,
+	}
+}
+
+// RegisterService records a service so its annotated methods get HTTP routes
+func (g *Gateway) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	g.services = append(g.services, service{desc: desc, impl: impl})
+}
+
+// RegisterRoutes adds a route to router for every HTTP binding of every
+// registered unary method
+func (g *Gateway) RegisterRoutes(router *mux.Router) {
+	for _, svc := range g.services {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(svc.desc.ServiceName))
+		if err != nil {
+			g.logger.Warnf("Skipping gateway routes for %s: %v", svc.desc.ServiceName, err)
+			continue
+		}
+		serviceDesc, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+
+		for i := range svc.desc.Methods {
+			method := svc.desc.Methods[i]
+			methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(method.MethodName))
+			if methodDesc == nil {
+				continue
+			}
+			rule, ok := proto.GetExtension(methodDesc.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if !ok || rule == nil {
+				continue
+			}
+
+			for _, binding := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+				if err := g.registerBinding(router, svc, method, methodDesc, binding); err != nil {
+					g.logger.Errorf("Failed to register gateway route for %s/%s: %v", svc.desc.ServiceName, method.MethodName, err)
+				}
+			}
+		}
+	}
+}
+
+// Routes returns the registered routes as method and path template, like
+// "GET /v1/health"
+func (g *Gateway) Routes() []string {
+	return g.routes
+}
+
+// Bindings returns the registered routes with the gRPC methods they call
+func (g *Gateway) Bindings() []Binding {
+	return g.bindings
+}
+
+// registerBinding adds the route for a single HTTP rule
+func (g *Gateway) registerBinding(
+	router *mux.Router,
+	svc service,
+	method grpc.MethodDesc,
+	methodDesc protoreflect.MethodDescriptor,
+	rule *annotations.HttpRule,
+) error {
+	httpMethod, pattern := ruleMethodAndPattern(rule)
+	if pattern == "" {
+		return fmt.Errorf("http rule has no pattern")
+	}
+
+	tmpl, err := parseTemplate(pattern)
+	if err != nil {
+		return err
+	}
+
+	router.Handle(tmpl.route, g.handler(svc, method, methodDesc, rule, tmpl)).Methods(httpMethod)
+	g.routes = append(g.routes, httpMethod+" "+tmpl.route)
+	g.bindings = append(g.bindings, Binding{
+		HTTPMethod: httpMethod,
+		Path:       tmpl.route,
+		FullMethod: fmt.Sprintf("/%s/%s", svc.desc.ServiceName, method.MethodName),
+	})
+	return nil
+}
+
+// handler returns the http.Handler transcoding requests for a method binding
+func (g *Gateway) handler(
+	svc service,
+	method grpc.MethodDesc,
+	methodDesc protoreflect.MethodDescriptor,
+	rule *annotations.HttpRule,
+	tmpl *template,
+) http.Handler {
+	fullMethod := fmt.Sprintf("/%s/%s", svc.desc.ServiceName, method.MethodName)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := &serverTransportStream{method: fullMethod}
+		ctx := grpc.NewContextWithServerTransportStream(r.Context(), stream)
+		ctx = metadata.NewIncomingContext(ctx, incomingMetadata(r))
+		if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+		}
+
+		if g.maxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, g.maxBodyBytes)
+		}
+		// tooLarge keeps the status of bodies over the limit, whose error is
+		// returned through the interceptors as InvalidArgument
+		tooLarge := false
+		dec := func(in any) error {
+			msg, ok := in.(proto.Message)
+			if !ok {
+				return status.Errorf(codes.Internal, "request of %s is not a proto message", fullMethod)
+			}
+			if err := g.bind(r, msg.ProtoReflect(), rule, tmpl); err != nil {
+				var maxBytesErr *http.MaxBytesError
+				tooLarge = errors.As(err, &maxBytesErr)
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+			return nil
+		}
+
+		resp, err := method.Handler(svc.impl, ctx, dec, g.interceptor)
+		writeMetadata(w, stream.header)
+		if err != nil {
+			if tooLarge && status.Code(err) == codes.InvalidArgument {
+				g.writeErrorStatus(w, err, http.StatusRequestEntityTooLarge)
+				return
+			}
+			g.writeError(w, err)
+			return
+		}
+
+		g.writeResponse(w, resp, methodDesc, rule)
+		writeTrailers(w, stream.trailer)
+	})
+}
+
+// writeResponse marshals the response, or the field named by response_body
+func (g *Gateway) writeResponse(w http.ResponseWriter, resp any, methodDesc protoreflect.MethodDescriptor, rule *annotations.HttpRule) {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		g.writeError(w, status.Errorf(codes.Internal, "response of %s is not a proto message", methodDesc.FullName()))
+		return
+	}
+
+	var body []byte
+	var err error
+	if field := rule.GetResponseBody(); field != "" {
+		body, err = marshalField(g.marshaler, msg.ProtoReflect(), field)
+	} else {
+		body, err = g.marshaler.Marshal(msg)
+	}
+	if err != nil {
+		g.writeError(w, status.Errorf(codes.Internal, "failed to marshal response: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// writeError writes the gRPC status of err as JSON with the mapped HTTP status
+func (g *Gateway) writeError(w http.ResponseWriter, err error) {
+	g.writeErrorStatus(w, err, HTTPStatusFromCode(status.Code(err)))
+}
+
+// writeErrorStatus writes the gRPC status of err as JSON with httpStatus
+func (g *Gateway) writeErrorStatus(w http.ResponseWriter, err error, httpStatus int) {
+	if marshalErr := writeStatus(w, status.Convert(err), httpStatus); marshalErr != nil {
+		g.logger.Errorf("Failed to marshal gateway error: %v", marshalErr)
+	}
+}
+
+// bind populates the request message from the body, path and query parameters
+func (g *Gateway) bind(r *http.Request, msg protoreflect.Message, rule *annotations.HttpRule, tmpl *template) error {
+	bound := map[string]bool{}
+
+	if body := rule.GetBody(); body != "" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
+		if len(data) > 0 {
+			if err := unmarshalBody(g.unmarshaler, data, msg, body); err != nil {
+				return err
+			}
+		}
+		bound[body] = true
+	}
+
+	vars := mux.Vars(r)
+	for _, name := range tmpl.fields {
+		if err := setField(msg, name, vars[name]); err != nil {
+			return fmt.Errorf("invalid path parameter %s: %w", name, err)
+		}
+		bound[name] = true
+	}
+
+	// Query parameters only bind fields not already covered by the body
+	if rule.GetBody() == "*" {
+		return nil
+	}
+	for key, values := range r.URL.Query() {
+		if bound[key] || (rule.GetBody() != "" && strings.HasPrefix(key, rule.GetBody()+".")) {
+			continue
+		}
+		for _, value := range values {
+			if err := setField(msg, key, value); err != nil {
+				return fmt.Errorf("invalid query parameter %s: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ruleMethodAndPattern returns the HTTP method and path template of a rule
+func ruleMethodAndPattern(rule *annotations.HttpRule) (string, string) {
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, pattern.Patch
+	case *annotations.HttpRule_Custom:
+		return pattern.Custom.GetKind(), pattern.Custom.GetPath()
+	}
+	return "", ""
+}
+
+// compile-time check that Gateway can be used wherever services are registered
+var _ grpc.ServiceRegistrar = (*Gateway)(nil)
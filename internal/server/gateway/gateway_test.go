@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/pkg/proto/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type commonService struct {
+	common.UnimplementedCommonServiceServer
+}
+
+func (commonService) HealthCheck(ctx context.Context, req *common.HealthCheckRequest) (*common.HealthCheckResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-fail")) > 0 {
+		return nil, status.Error(codes.Unavailable, "draining")
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("x-served-by", "test"))
+	return &common.HealthCheckResponse{Status: true}, nil
+}
+
+func TestGatewayRoutes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	var intercepted []string
+	g := NewGateway(GatewayParams{
+		Logger: logger,
+		Interceptor: func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			intercepted = append(intercepted, info.FullMethod)
+			return handler(ctx, req)
+		},
+	})
+	common.RegisterCommonServiceServer(g, commonService{})
+	router := mux.NewRouter()
+	g.RegisterRoutes(router)
+
+	wantBindings := []Binding{
+		{HTTPMethod: http.MethodGet, Path: "/v1/health", FullMethod: common.CommonService_HealthCheck_FullMethodName},
+		{HTTPMethod: http.MethodGet, Path: "/v1/version", FullMethod: common.CommonService_GetVersion_FullMethodName},
+	}
+	if got := g.Bindings(); !reflect.DeepEqual(got, wantBindings) {
+		t.Errorf("Bindings() = %v, want %v", got, wantBindings)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"status":true}` {
+		t.Errorf("GET /v1/health = %d %s, want 200 with the status", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Grpc-Metadata-X-Served-By"); got != "test" {
+		t.Errorf("Grpc-Metadata-X-Served-By = %q, want test", got)
+	}
+
+	rec = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	r.Header.Set("X-Fail", "1")
+	router.ServeHTTP(rec, r)
+	var st struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+		t.Fatalf("failed to decode the error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || st.Code != int(codes.Unavailable) || st.Message != "draining" {
+		t.Errorf("GET /v1/health failing = %d %s, want 503 with the status", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/version", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("GET /v1/version = %d, want 501 from the unimplemented method", rec.Code)
+	}
+
+	wantIntercepted := []string{
+		common.CommonService_HealthCheck_FullMethodName,
+		common.CommonService_HealthCheck_FullMethodName,
+		common.CommonService_GetVersion_FullMethodName,
+	}
+	if !reflect.DeepEqual(intercepted, wantIntercepted) {
+		t.Errorf("intercepted = %v, want %v", intercepted, wantIntercepted)
+	}
+}
+
+func TestWriteErrorRetryAfter(t *testing.T) {
+	st, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(1500 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := WriteError(rec, st.Err()); err != nil {
+		t.Fatalf("WriteError() error = %v", err)
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want the delay rounded up", got)
+	}
+}
+
+func TestGatewayBodyLimit(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	g := NewGateway(GatewayParams{Logger: logger, MaxBodyBytes: 64})
+
+	methodDesc := (&common.HealthCheckRequest{}).ProtoReflect().Descriptor().ParentFile().
+		Services().ByName("CommonService").Methods().ByName("HealthCheck")
+	rule := &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/health"}, Body: "*"}
+	tmpl, err := parseTemplate("/v1/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service{desc: &common.CommonService_ServiceDesc, impl: commonService{}}
+	handler := g.handler(svc, common.CommonService_ServiceDesc.Methods[0], methodDesc, rule, tmpl)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "within the limit", body: `{"unknown":"` + strings.Repeat("a", 32) + `"}`, want: http.StatusOK},
+		{name: "over the limit", body: `{"unknown":"` + strings.Repeat("a", 64) + `"}`, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/health", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+			if tt.want != http.StatusOK && !strings.Contains(rec.Body.String(), `"code":3`) {
+				t.Errorf("body = %s, want an InvalidArgument status", rec.Body)
+			}
+		})
+	}
+}
+
+func TestGatewayMarshalsProtoNames(t *testing.T) {
+	g := NewGateway(GatewayParams{Logger: logrus.New()})
+	body, err := g.marshaler.Marshal(&common.GetVersionResponse{Version: "1.0.0", BuildDate: "today"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"version": "1.0.0", "commit": "", "build_date": "today", "go_version": "", "platform": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal() = %s, want the proto field names", body)
+	}
+}
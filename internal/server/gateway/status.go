@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// WriteError writes err as a JSON google.rpc.Status with the equivalent HTTP
+// status, the format of every error returned by transcoded calls. A RetryInfo
+// detail is also exposed as a Retry-After header. It returns
+// the marshaling error, in which case a generic internal error is written.
+func WriteError(w http.ResponseWriter, err error) error {
+	st := status.Convert(err)
+	return writeStatus(w, st, HTTPStatusFromCode(st.Code()))
+}
+
+// writeStatus writes st as JSON with httpStatus, see WriteError
+func writeStatus(w http.ResponseWriter, st *status.Status, httpStatus int) error {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			seconds := int(math.Ceil(info.GetRetryDelay().AsDuration().Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, seconds)))
+		}
+	}
+
+	body, marshalErr := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(st.Proto())
+	if marshalErr != nil {
+		body = []byte(`{"code":13,"message":"failed to marshal error"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(body)
+	return marshalErr
+}
+
+// HTTPStatusFromCode maps a gRPC status code to the equivalent HTTP status, see
+// https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// 499 Client Closed Request has no constant in net/http
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// template is a google.api.http path template converted to a gorilla/mux route
+type template struct {
+	// route is the gorilla/mux path template
+	route string
+	// fields are the request field paths bound from path variables
+	fields []string
+}
+
+// parseTemplate converts a path template such as "/v1/{name=shelves/*}/books"
+// into a gorilla/mux route, keeping the variable names as request field paths
+func parseTemplate(pattern string) (*template, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("path template %q must start with /", pattern)
+	}
+
+	tmpl := &template{}
+	var route strings.Builder
+	wildcards := 0
+
+	for i := 0; i < len(pattern); {
+		switch {
+		case pattern[i] == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("path template %q has an unterminated variable", pattern)
+			}
+			name, segments, hasSegments := strings.Cut(pattern[i+1:i+end], "=")
+			if name == "" {
+				return nil, fmt.Errorf("path template %q has an unnamed variable", pattern)
+			}
+			tmpl.fields = append(tmpl.fields, name)
+			if !hasSegments || segments == "*" {
+				fmt.Fprintf(&route, "{%s}", name)
+			} else {
+				fmt.Fprintf(&route, "{%s:%s}", name, segmentsRegexp(segments))
+			}
+			i += end + 1
+
+		case pattern[i] == '*':
+			// Unnamed wildcards still need a variable name in gorilla/mux
+			regex := "[^/]+"
+			if strings.HasPrefix(pattern[i:], "**") {
+				regex = ".+"
+				i++
+			}
+			fmt.Fprintf(&route, "{_wildcard%d:%s}", wildcards, regex)
+			wildcards++
+			i++
+
+		default:
+			route.WriteByte(pattern[i])
+			i++
+		}
+	}
+
+	tmpl.route = route.String()
+	return tmpl, nil
+}
+
+// segmentsRegexp converts the segments of a variable into a regular expression
+func segmentsRegexp(segments string) string {
+	parts := strings.Split(segments, "/")
+	for i, part := range parts {
+		switch part {
+		case "*":
+			parts[i] = "[^/]+"
+		case "**":
+			parts[i] = ".+"
+		default:
+			parts[i] = regexp.QuoteMeta(part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		wantRoute  string
+		wantFields []string
+	}{
+		{pattern: "/v1/health", wantRoute: "/v1/health"},
+		{pattern: "/v1/books/{id}", wantRoute: "/v1/books/{id}", wantFields: []string{"id"}},
+		{pattern: "/v1/books/{id=*}", wantRoute: "/v1/books/{id}", wantFields: []string{"id"}},
+		{
+			pattern:    "/v1/{name=shelves/*/books/*}",
+			wantRoute:  "/v1/{name:shelves/[^/]+/books/[^/]+}",
+			wantFields: []string{"name"},
+		},
+		{pattern: "/v1/{path=files/**}", wantRoute: "/v1/{path:files/.+}", wantFields: []string{"path"}},
+		{
+			pattern:    "/v1/shelves/{shelf.id}/books/{book_id}",
+			wantRoute:  "/v1/shelves/{shelf.id}/books/{book_id}",
+			wantFields: []string{"shelf.id", "book_id"},
+		},
+		// Literal segments are quoted in variable regular expressions
+		{pattern: "/v1/{name=a.b/*}", wantRoute: `/v1/{name:a\.b/[^/]+}`, wantFields: []string{"name"}},
+		// Unnamed wildcards match without binding a field
+		{pattern: "/v1/*/books/**", wantRoute: "/v1/{_wildcard0:[^/]+}/books/{_wildcard1:.+}"},
+		{pattern: "/v1/books/{id}:publish", wantRoute: "/v1/books/{id}:publish", wantFields: []string{"id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.pattern)
+			if err != nil {
+				t.Fatalf("parseTemplate() error = %v", err)
+			}
+			if tmpl.route != tt.wantRoute {
+				t.Errorf("route = %q, want %q", tmpl.route, tt.wantRoute)
+			}
+			if !reflect.DeepEqual(tmpl.fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", tmpl.fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr string
+	}{
+		{pattern: "v1/books", wantErr: "must start with /"},
+		{pattern: "/v1/books/{id", wantErr: "unterminated variable"},
+		{pattern: "/v1/books/{=*}", wantErr: "unnamed variable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			_, err := parseTemplate(tt.pattern)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseTemplate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateRoutes(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		wantVars map[string]string
+	}{
+		{pattern: "/v1/{name=shelves/*}", path: "/v1/shelves/1", wantVars: map[string]string{"name": "shelves/1"}},
+		{pattern: "/v1/{name=shelves/*}", path: "/v1/shelves/1/books"},
+		{pattern: "/v1/{name=shelves/*}", path: "/v1/authors/1"},
+		{pattern: "/v1/{path=files/**}", path: "/v1/files/a/b/c.txt", wantVars: map[string]string{"path": "files/a/b/c.txt"}},
+		{pattern: "/v1/{path=files/**}", path: "/v1/files/"},
+		{pattern: "/v1/books/{id}", path: "/v1/books/42", wantVars: map[string]string{"id": "42"}},
+		{pattern: "/v1/books/{id}", path: "/v1/books/42/pages"},
+		{pattern: "/v1/{name=a.b/*}", path: "/v1/axb/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.pattern)
+			if err != nil {
+				t.Fatalf("parseTemplate() error = %v", err)
+			}
+			var vars map[string]string
+			router := mux.NewRouter()
+			router.HandleFunc(tmpl.route, func(w http.ResponseWriter, r *http.Request) {
+				vars = mux.Vars(r)
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+}
@@ -2,50 +2,91 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"runtime/debug"
+	"sync/atomic"
 
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/authz"
 	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/Gambitier/voidkitgo/internal/metrics"
 	grpcHandlers "github.com/Gambitier/voidkitgo/internal/server/handlers/grpc"
 	"github.com/Gambitier/voidkitgo/internal/services"
+	"github.com/Gambitier/voidkitgo/internal/tracing"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
 )
 
 type GrpcServer interface {
-	// Start starts the server on the specified port
-	Start(port int) error
-	// Shutdown gracefully stops the server
-	Shutdown()
+	Component
 	// Port returns the port the server is listening on
 	Port() int
+	// RegisterServices registers the gRPC handlers with another registrar
+	RegisterServices(registrar grpc.ServiceRegistrar)
+	// UnaryInterceptor returns the interceptor chain applied to unary calls
+	UnaryInterceptor() grpc.UnaryServerInterceptor
 }
 
 type grpcServer struct {
-	server    *grpc.Server
-	serverEnv config.Environment
-	port      int
-	logger    *logrus.Logger
-	handlers  *grpcHandlers.GrpcHandlers
+	server   *grpc.Server
+	config   *config.GRPCConfig
+	port     int
+	logger   *logrus.Logger
+	handlers *grpcHandlers.GrpcHandlers
+	unary    grpc.UnaryServerInterceptor
+	listen   listenFunc
+	ready    atomic.Bool
+	// public methods skip authentication and authorization
+	public map[string]bool
 }
 
 type GrpcServerParams struct {
-	Services  *services.Services
-	Logger    *logrus.Logger
-	ServerEnv config.Environment
+	Services *services.Services
+	Logger   *logrus.Logger
+	Config   *config.GRPCConfig
+	// Profile enables reflection and verbose errors
+	Profile config.Profile
+	// Metrics instruments every registered method, nil disables it
+	Metrics *metrics.Metrics
+	// Tracing starts a span for every call, nil disables it
+	Tracing *tracing.Provider
+	// Listen overrides the listener, by default the configured port is bound
+	Listen func() (net.Listener, error)
+	// TLS serves with transport credentials from the given configuration, nil serves plaintext
+	TLS *tls.Config
+	// Auth requires a bearer token on non-public methods, nil disables it
+	Auth *auth.Authenticator
+	// Authz enforces method policies on non-public methods, nil disables it
+	Authz *authz.Engine
+	// RateLimits bounds call rates and concurrency, nil disables it
+	RateLimits *RateLimits
+}
+
+// panicError returns the error of a recovered panic, carrying the panic
+// message when verbose
+func panicError(r any, verbose bool) error {
+	if verbose {
+		return status.Errorf(codes.Internal, "Internal server error: %v", r)
+	}
+	return status.Errorf(codes.Internal, "Internal server error")
 }
 
 // panicRecoveryUnaryInterceptor returns a new unary server interceptor for panic recovery
-func panicRecoveryUnaryInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+func panicRecoveryUnaryInterceptor(logger *logrus.Logger, verbose bool) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		defer func() {
 			if r := recover(); r != nil {
-				logger.Errorf("Recovered from panic in gRPC handler: %v\nStack trace:\n%s", r, debug.Stack())
-				err = status.Errorf(codes.Internal, "Internal server error")
+				logging.FromContext(ctx, logger).Errorf("Recovered from panic in gRPC handler: %v\nStack trace:\n%s", r, debug.Stack())
+				err = panicError(r, verbose)
 			}
 		}()
 		return handler(ctx, req)
@@ -53,49 +94,153 @@ func panicRecoveryUnaryInterceptor(logger *logrus.Logger) grpc.UnaryServerInterc
 }
 
 // panicRecoveryStreamInterceptor returns a new stream server interceptor for panic recovery
-func panicRecoveryStreamInterceptor(logger *logrus.Logger) grpc.StreamServerInterceptor {
+func panicRecoveryStreamInterceptor(logger *logrus.Logger, verbose bool) grpc.StreamServerInterceptor {
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
 		defer func() {
 			if r := recover(); r != nil {
-				logger.Errorf("Recovered from panic in gRPC stream handler: %v\nStack trace:\n%s", r, debug.Stack())
-				err = status.Errorf(codes.Internal, "Internal server error")
+				logging.FromContext(stream.Context(), logger).Errorf("Recovered from panic in gRPC stream handler: %v\nStack trace:\n%s", r, debug.Stack())
+				err = panicError(r, verbose)
 			}
 		}()
 		return handler(srv, stream)
 	}
 }
 
+// chainUnaryInterceptors combines interceptors into one, the first being the outermost
+func chainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
+
 // NewGrpcServer creates a new gRPC server
 func NewGrpcServer(params GrpcServerParams) GrpcServer {
 	grpcHandlers := grpcHandlers.NewGrpcHandlers(params.Services)
 
+	// The unary chain is shared with the HTTP gateway so both transports
+	// go through the same interceptors. Panic recovery is the outermost, so a
+	// panic in any other interceptor is recovered too.
+	unaryInterceptors := []grpc.UnaryServerInterceptor{panicRecoveryUnaryInterceptor(params.Logger, params.Profile.VerboseErrors)}
+	streamInterceptors := []grpc.StreamServerInterceptor{panicRecoveryStreamInterceptor(params.Logger, params.Profile.VerboseErrors)}
+	if !params.Profile.VerboseErrors {
+		unaryInterceptors = append(unaryInterceptors, hideInternalErrorsUnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, hideInternalErrorsStreamInterceptor())
+	}
+	if params.TLS != nil {
+		unaryInterceptors = append(unaryInterceptors, peerUnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, peerStreamInterceptor())
+	}
+	if params.Tracing != nil {
+		unaryInterceptors = append(unaryInterceptors, tracingUnaryInterceptor(params.Tracing))
+		streamInterceptors = append(streamInterceptors, tracingStreamInterceptor(params.Tracing))
+	}
+	unaryInterceptors = append(unaryInterceptors, requestLoggingUnaryInterceptor(params.Logger))
+	streamInterceptors = append(streamInterceptors, requestLoggingStreamInterceptor(params.Logger))
+	if params.Services.Container != nil {
+		unaryInterceptors = append(unaryInterceptors, scopeUnaryInterceptor(params.Services.Container, params.Logger))
+		streamInterceptors = append(streamInterceptors, scopeStreamInterceptor(params.Services.Container, params.Logger))
+	}
+	if params.Metrics != nil {
+		unaryInterceptors = append(unaryInterceptors, metricsUnaryInterceptor(params.Metrics))
+		streamInterceptors = append(streamInterceptors, metricsStreamInterceptor(params.Metrics))
+	}
+	public := map[string]bool{}
+	for _, method := range grpcHandlers.PublicMethods() {
+		public[method] = true
+	}
+	if params.Profile.Reflection {
+		public[reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName] = true
+		public[reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName] = true
+	}
+	if params.RateLimits != nil && params.RateLimits.inFlight != nil {
+		unaryInterceptors = append(unaryInterceptors, concurrencyUnaryInterceptor(params.RateLimits))
+		streamInterceptors = append(streamInterceptors, concurrencyStreamInterceptor(params.RateLimits))
+	}
+	if params.Auth != nil {
+		unaryInterceptors = append(unaryInterceptors, authUnaryInterceptor(params.Auth, public, params.Logger))
+		streamInterceptors = append(streamInterceptors, authStreamInterceptor(params.Auth, public, params.Logger))
+	}
+	if params.RateLimits != nil {
+		unaryInterceptors = append(unaryInterceptors, rateLimitUnaryInterceptor(params.RateLimits))
+		streamInterceptors = append(streamInterceptors, rateLimitStreamInterceptor(params.RateLimits))
+	}
+	if params.Authz != nil {
+		unaryInterceptors = append(unaryInterceptors, authzUnaryInterceptor(params.Authz, public))
+		streamInterceptors = append(streamInterceptors, authzStreamInterceptor(params.Authz, public))
+	}
+
+	unary := chainUnaryInterceptors(unaryInterceptors...)
+
+	// Create gRPC server with panic recovery, tracing, request logging, request scope and metrics interceptors
+	serverOptions := []grpc.ServerOption{
+		grpc.UnaryInterceptor(unary),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+	if params.TLS != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(params.TLS)))
+	}
+	server := grpc.NewServer(serverOptions...)
+
+	grpcHandlers.RegisterServices(server)
+
+	if params.Metrics != nil {
+		params.Metrics.InitializeGRPC(server.GetServiceInfo())
+	}
+
+	if params.Authz != nil {
+		var serviceNames []string
+		for name := range server.GetServiceInfo() {
+			serviceNames = append(serviceNames, name)
+		}
+		if err := params.Authz.LoadServiceOptions(serviceNames...); err != nil {
+			params.Logger.Errorf("Failed to load authorization policies: %v", err)
+		}
+	}
+
+	if params.Profile.Reflection {
+		reflection.Register(server)
+	}
+
+	listen := params.Listen
+	if listen == nil {
+		listen = tcpListener("", params.Config.Port)
+	}
+
 	return &grpcServer{
-		logger:    params.Logger,
-		serverEnv: params.ServerEnv,
-		handlers:  grpcHandlers,
+		server:   server,
+		listen:   listen,
+		logger:   params.Logger,
+		config:   params.Config,
+		port:     params.Config.Port,
+		handlers: grpcHandlers,
+		unary:    unary,
+		public:   public,
 	}
 }
 
-// Start starts the gRPC server
-func (s *grpcServer) Start(port int) error {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+// Name returns the component name
+func (s *grpcServer) Name() string {
+	return "grpc server"
+}
+
+// Start starts the gRPC server and blocks until it is stopped
+func (s *grpcServer) Start(ctx context.Context) error {
+	lis, err := s.listen()
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
-	// Create gRPC server with panic recovery interceptors
-	s.server = grpc.NewServer(
-		grpc.UnaryInterceptor(panicRecoveryUnaryInterceptor(s.logger)),
-		grpc.StreamInterceptor(panicRecoveryStreamInterceptor(s.logger)),
-	)
-
-	s.handlers.RegisterServices(s.server)
-
-	if s.serverEnv.IsDevelopment() {
-		reflection.Register(s.server)
-	}
+	s.logger.Infof("Starting gRPC server on %s", lis.Addr())
+	s.ready.Store(true)
+	defer s.ready.Store(false)
 
-	s.port = port
 	if err := s.server.Serve(lis); err != nil {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
@@ -103,14 +248,39 @@ func (s *grpcServer) Start(port int) error {
 	return nil
 }
 
-// Shutdown gracefully stops the gRPC server
-func (s *grpcServer) Shutdown() {
-	if s.server != nil {
+// Stop gracefully stops the gRPC server, forcing it to stop once ctx expires
+func (s *grpcServer) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
 		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return fmt.Errorf("forced stop: %w", ctx.Err())
 	}
 }
 
+// Ready reports whether the gRPC server is accepting connections
+func (s *grpcServer) Ready() bool {
+	return s.ready.Load()
+}
+
 // Port returns the port the server is listening on
 func (s *grpcServer) Port() int {
 	return s.port
 }
+
+// RegisterServices registers the gRPC handlers with another registrar
+func (s *grpcServer) RegisterServices(registrar grpc.ServiceRegistrar) {
+	s.handlers.RegisterServices(registrar)
+}
+
+// UnaryInterceptor returns the interceptor chain applied to unary calls
+func (s *grpcServer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return s.unary
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 
+	"github.com/Gambitier/voidkitgo/internal/buildinfo"
 	"github.com/Gambitier/voidkitgo/internal/services"
 	"github.com/Gambitier/voidkitgo/pkg/proto/common"
 )
@@ -19,9 +20,33 @@ func NewCommonServiceHandler(services *services.Services) *Handler {
 	}
 }
 
+// PublicMethods returns the methods callable without authentication
+func (h *Handler) PublicMethods() []string {
+	return []string{
+		common.CommonService_HealthCheck_FullMethodName,
+		common.CommonService_GetVersion_FullMethodName,
+	}
+}
+
 func (h *Handler) HealthCheck(
 	ctx context.Context,
 	req *common.HealthCheckRequest,
 ) (*common.HealthCheckResponse, error) {
-	return &common.HealthCheckResponse{Status: true}, nil
+	report := h.services.Health.Readiness(ctx)
+	return &common.HealthCheckResponse{Status: report.Healthy}, nil
+}
+
+// GetVersion returns the build information of the running service
+func (h *Handler) GetVersion(
+	ctx context.Context,
+	req *common.GetVersionRequest,
+) (*common.GetVersionResponse, error) {
+	info := buildinfo.Get()
+	return &common.GetVersionResponse{
+		Version:   info.Version,
+		Commit:    info.Commit,
+		BuildDate: info.BuildDate,
+		GoVersion: info.GoVersion,
+		Platform:  info.Platform,
+	}, nil
 }
@@ -2,24 +2,44 @@ package grpc
 
 import (
 	"github.com/Gambitier/voidkitgo/internal/server/handlers/grpc/common"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/grpc/health"
 	"github.com/Gambitier/voidkitgo/internal/services"
 	commonProto "github.com/Gambitier/voidkitgo/pkg/proto/common"
 	"google.golang.org/grpc"
+	healthProto "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type GrpcHandlers struct {
 	CommonServiceHandler *common.Handler
+	HealthServiceHandler *health.Handler
 }
 
 func NewGrpcHandlers(services *services.Services) *GrpcHandlers {
 	commonServiceHandler := common.NewCommonServiceHandler(services)
+	healthServiceHandler := health.NewHealthServiceHandler(
+		services,
+		commonProto.CommonService_ServiceDesc.ServiceName,
+	)
 
 	return &GrpcHandlers{
 		CommonServiceHandler: commonServiceHandler,
+		HealthServiceHandler: healthServiceHandler,
 	}
 }
 
-func (h *GrpcHandlers) RegisterServices(server *grpc.Server) {
+// PublicMethods returns the full names of the methods callable without
+// authentication, every other method requires a bearer token
+func (h *GrpcHandlers) PublicMethods() []string {
+	var methods []string
+	methods = append(methods, h.CommonServiceHandler.PublicMethods()...)
+	methods = append(methods, h.HealthServiceHandler.PublicMethods()...)
+	return methods
+}
+
+// RegisterServices registers every service server with the registrar, which is
+// either the gRPC server itself or the HTTP gateway
+func (h *GrpcHandlers) RegisterServices(server grpc.ServiceRegistrar) {
 	// register new service servers here
 	commonProto.RegisterCommonServiceServer(server, h.CommonServiceHandler)
+	healthProto.RegisterHealthServer(server, h.HealthServiceHandler)
 }
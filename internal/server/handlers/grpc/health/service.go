@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/health"
+	"github.com/Gambitier/voidkitgo/internal/services"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// watchInterval is how often Watch re-evaluates the checks
+const watchInterval = 5 * time.Second
+
+// Handler implements the standard grpc.health.v1.Health service backed by
+// the health registry. The empty service name and the names of the served
+// gRPC services report overall readiness, any other name reports the check
+// registered under that name.
+type Handler struct {
+	healthpb.UnimplementedHealthServer
+	registry     *health.Registry
+	grpcServices map[string]bool
+}
+
+// NewHealthServiceHandler creates a new health service handler
+func NewHealthServiceHandler(services *services.Services, grpcServices ...string) *Handler {
+	names := map[string]bool{"": true}
+	for _, name := range grpcServices {
+		names[name] = true
+	}
+
+	return &Handler{
+		registry:     services.Health,
+		grpcServices: names,
+	}
+}
+
+// PublicMethods returns the methods callable without authentication, health
+// probes must be reachable by orchestrators
+func (h *Handler) PublicMethods() []string {
+	return []string{healthpb.Health_Check_FullMethodName, healthpb.Health_Watch_FullMethodName}
+}
+
+func (h *Handler) Check(
+	ctx context.Context,
+	req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	servingStatus, ok := h.servingStatus(ctx, req.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+func (h *Handler) Watch(
+	req *healthpb.HealthCheckRequest,
+	stream healthpb.Health_WatchServer,
+) error {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		servingStatus, ok := h.servingStatus(stream.Context(), req.GetService())
+		if !ok {
+			servingStatus = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+
+		// Only changes are sent, the first evaluation always differs from UNKNOWN
+		if servingStatus != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			last = servingStatus
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// servingStatus evaluates the status of service, reporting false if it is unknown
+func (h *Handler) servingStatus(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	if h.grpcServices[service] {
+		return toServingStatus(h.registry.Readiness(ctx).Healthy), true
+	}
+
+	result, ok := h.registry.Check(ctx, service)
+	if !ok {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+	return toServingStatus(result.Healthy), true
+}
+
+func toServingStatus(healthy bool) healthpb.HealthCheckResponse_ServingStatus {
+	if healthy {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,126 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// supportedLevels are the levels that can be set at runtime, matching the
+// levels accepted by logging.level
+var supportedLevels = map[string]bool{
+	"debug": true,
+	"info":  true,
+	"warn":  true,
+	"error": true,
+}
+
+type adminHandler struct {
+	logger      *logrus.Logger
+	metrics     http.Handler
+	metricsPath string
+	pprof       bool
+	config      func() map[string]any
+	readOnly    bool
+}
+
+// logLevelRequest is the body of a log level change
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+type AdminHandlerParams struct {
+	Logger *logrus.Logger
+	// Metrics is served on MetricsPath, nil disables it
+	Metrics     http.Handler
+	MetricsPath string
+	// Pprof serves the runtime profiles under /debug/pprof/
+	Pprof bool
+	// Config returns the redacted running config served on /debug/config,
+	// nil disables it
+	Config func() map[string]any
+	// ReadOnly leaves out the route changing the log level
+	ReadOnly bool
+}
+
+// NewAdminHandler creates the admin handler
+func NewAdminHandler(params AdminHandlerParams) common.HttpHandler {
+	return &adminHandler{
+		logger:      params.Logger,
+		metrics:     params.Metrics,
+		metricsPath: params.MetricsPath,
+		pprof:       params.Pprof,
+		config:      params.Config,
+		readOnly:    params.ReadOnly,
+	}
+}
+
+// register routes
+func (h *adminHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/log-level", h.HandleGetLogLevel).Methods(http.MethodGet)
+	if !h.readOnly {
+		router.HandleFunc("/admin/log-level", h.HandleSetLogLevel).Methods(http.MethodPut)
+	}
+	if h.metrics != nil {
+		router.Handle(h.metricsPath, h.metrics).Methods(http.MethodGet)
+	}
+	if h.config != nil {
+		router.HandleFunc("/debug/config", h.HandleGetConfig).Methods(http.MethodGet)
+	}
+	if h.pprof {
+		router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		// The index also serves the named profiles, such as heap and goroutine
+		router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	}
+}
+
+// metrics are public so Prometheus can scrape them, changing the log level
+// and the debug endpoints require authentication
+func (h *adminHandler) PublicRoutes() []string {
+	if h.metrics == nil {
+		return nil
+	}
+	return []string{http.MethodGet + " " + h.metricsPath}
+}
+
+func (h *adminHandler) HandleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"level": h.logger.GetLevel().String()})
+}
+
+// HandleSetLogLevel changes the level of the running logger without a restart
+func (h *adminHandler) HandleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if !supportedLevels[req.Level] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "level must be one of debug, info, warn, error"})
+		return
+	}
+
+	level, _ := logrus.ParseLevel(req.Level)
+	previous := h.logger.GetLevel()
+	h.logger.SetLevel(level)
+	h.logger.Infof("Log level changed from %s to %s", previous, level)
+
+	writeJSON(w, http.StatusOK, map[string]string{"level": level.String()})
+}
+
+// HandleGetConfig returns the running config with secrets redacted
+func (h *adminHandler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.config())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -5,3 +5,22 @@ import "github.com/gorilla/mux"
 type HttpHandler interface {
 	RegisterRoutes(router *mux.Router)
 }
+
+// PublicRouter is implemented by handlers exposing routes that are reachable
+// without authentication, every other route requires a bearer token
+type PublicRouter interface {
+	// PublicRoutes returns the public routes as method and path template,
+	// like "GET /health", so other methods of the same path stay protected
+	PublicRoutes() []string
+}
+
+// PublicRoutes returns the public routes of every handler implementing PublicRouter
+func PublicRoutes(handlers ...HttpHandler) []string {
+	var routes []string
+	for _, handler := range handlers {
+		if router, ok := handler.(PublicRouter); ok {
+			routes = append(routes, router.PublicRoutes()...)
+		}
+	}
+	return routes
+}
@@ -4,24 +4,90 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/Gambitier/voidkitgo/internal/health"
 	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/common"
+	"github.com/Gambitier/voidkitgo/internal/services"
 	"github.com/gorilla/mux"
 )
 
 type healthHandler struct {
-	server *http.Server
+	registry *health.Registry
 }
 
-func NewHealthHandler(server *http.Server) common.HttpHandler {
-	return &healthHandler{server: server}
+// checkResponse is the per-check detail of a probe response
+type checkResponse struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// probeResponse is the body of the liveness and readiness probes
+type probeResponse struct {
+	Status string          `json:"status"`
+	Checks []checkResponse `json:"checks"`
+}
+
+// NewHealthHandler creates the probe handlers reporting the checks of the
+// health registry
+func NewHealthHandler(services *services.Services) common.HttpHandler {
+	return &healthHandler{registry: services.Health}
 }
 
 // register routes
 func (h *healthHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/health", h.HandleHealthCheck).Methods(http.MethodGet)
+	router.HandleFunc("/livez", h.HandleLiveness).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", h.HandleReadiness).Methods(http.MethodGet)
 }
 
+// probes are public so orchestrators and load balancers can reach them
+func (h *healthHandler) PublicRoutes() []string {
+	return []string{"GET /health", "GET /livez", "GET /readyz"}
+}
+
+// HandleHealthCheck reports readiness without the per-check detail
 func (h *healthHandler) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	if !h.registry.Readiness(r.Context()).Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unavailable"})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
+
+func (h *healthHandler) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.registry.Liveness(r.Context()))
+}
+
+func (h *healthHandler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.registry.Readiness(r.Context()))
+}
+
+// writeReport writes the report with per-check detail, failing with 503 if
+// any check is unhealthy
+func writeReport(w http.ResponseWriter, report health.Report) {
+	resp := probeResponse{Status: "ok", Checks: make([]checkResponse, 0, len(report.Checks))}
+	for _, result := range report.Checks {
+		check := checkResponse{
+			Name:       result.Name,
+			Status:     "ok",
+			Error:      result.Error,
+			DurationMs: float64(result.Duration.Microseconds()) / 1000,
+		}
+		if !result.Healthy {
+			check.Status = "unavailable"
+		}
+		resp.Checks = append(resp.Checks, check)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		resp.Status = "unavailable"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/internal/health"
+	"github.com/Gambitier/voidkitgo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+func TestProbes(t *testing.T) {
+	var failing error
+	registry := health.NewRegistry()
+	registry.RegisterLiveness("process", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+	registry.RegisterReadiness("cache", health.CheckerFunc(func(ctx context.Context) error { return failing }))
+	router := mux.NewRouter()
+	NewHealthHandler(&services.Services{Health: registry}).RegisterRoutes(router)
+
+	tests := []struct {
+		name     string
+		path     string
+		failing  error
+		wantCode int
+		wantBody string
+	}{
+		{name: "healthy", path: "/health", wantCode: http.StatusOK, wantBody: `{"status":"ok"}`},
+		{name: "unhealthy", path: "/health", failing: errors.New("down"), wantCode: http.StatusServiceUnavailable, wantBody: `{"status":"unavailable"}`},
+		{name: "live", path: "/livez", failing: errors.New("down"), wantCode: http.StatusOK, wantBody: `"name":"process","status":"ok"`},
+		{name: "ready", path: "/readyz", wantCode: http.StatusOK, wantBody: `"name":"cache","status":"ok"`},
+		{name: "not ready", path: "/readyz", failing: errors.New("down"), wantCode: http.StatusServiceUnavailable, wantBody: `"status":"unavailable","error":"down"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing = tt.failing
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET %s = %d %s, want %d with %s", tt.path, rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+		})
+	}
+}
@@ -1,23 +1,52 @@
 package http
 
 import (
-	"net/http"
-
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/admin"
 	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/common"
 	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/health"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/version"
+	"github.com/Gambitier/voidkitgo/internal/services"
 	"github.com/gorilla/mux"
 )
 
 type httpHandlers struct {
-	healthHandler common.HttpHandler
+	healthHandler  common.HttpHandler
+	versionHandler common.HttpHandler
 }
 
-func NewHttpHandlers(server *http.Server) common.HttpHandler {
+// NewHttpHandlers creates the handlers served by the HTTP server
+func NewHttpHandlers(services *services.Services) common.HttpHandler {
 	return &httpHandlers{
-		healthHandler: health.NewHealthHandler(server),
+		healthHandler:  health.NewHealthHandler(services),
+		versionHandler: version.NewVersionHandler(),
 	}
 }
 
 func (h *httpHandlers) RegisterRoutes(router *mux.Router) {
 	h.healthHandler.RegisterRoutes(router)
+	h.versionHandler.RegisterRoutes(router)
+}
+
+func (h *httpHandlers) PublicRoutes() []string {
+	return common.PublicRoutes(h.healthHandler, h.versionHandler)
+}
+
+type adminHttpHandlers struct {
+	adminHandler common.HttpHandler
+}
+
+// NewAdminHttpHandlers creates the admin handlers, served either by the HTTP
+// server or by the admin server when an admin port is configured
+func NewAdminHttpHandlers(params admin.AdminHandlerParams) common.HttpHandler {
+	return &adminHttpHandlers{
+		adminHandler: admin.NewAdminHandler(params),
+	}
+}
+
+func (h *adminHttpHandlers) RegisterRoutes(router *mux.Router) {
+	h.adminHandler.RegisterRoutes(router)
+}
+
+func (h *adminHttpHandlers) PublicRoutes() []string {
+	return common.PublicRoutes(h.adminHandler)
 }
@@ -0,0 +1,32 @@
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Gambitier/voidkitgo/internal/buildinfo"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/common"
+	"github.com/gorilla/mux"
+)
+
+type versionHandler struct{}
+
+// NewVersionHandler creates the handler exposing the build information
+func NewVersionHandler() common.HttpHandler {
+	return &versionHandler{}
+}
+
+// register routes
+func (h *versionHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/version", h.HandleVersion).Methods(http.MethodGet)
+}
+
+// the version is public so deployments can be checked without credentials
+func (h *versionHandler) PublicRoutes() []string {
+	return []string{"GET /version"}
+}
+
+func (h *versionHandler) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildinfo.Get())
+}
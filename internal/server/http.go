@@ -2,73 +2,265 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"sync/atomic"
 
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/authz"
 	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/di"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/Gambitier/voidkitgo/internal/metrics"
+	"github.com/Gambitier/voidkitgo/internal/server/gateway"
 	httpHandlers "github.com/Gambitier/voidkitgo/internal/server/handlers/http"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/common"
 	"github.com/Gambitier/voidkitgo/internal/services"
+	"github.com/Gambitier/voidkitgo/internal/tracing"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
-// httpServer represents the HTTP server
-type httpServer struct {
+// routeAccess holds what the authentication and authorization middleware
+// need to protect the routes of a router
+type routeAccess struct {
 	router *mux.Router
-	server *http.Server
 	logger *logrus.Logger
+	auth   *auth.Authenticator
+	authz  *authz.Engine
+	// publicRoutes skip authentication and authorization, they are keyed by
+	// routeKey
+	publicRoutes map[string]bool
+	// gatewayRoutes, keyed by routeKey, are transcoded to gRPC calls, which go through the
+	// interceptors instead of the auth and limiting middleware
+	gatewayRoutes map[string]bool
+}
+
+// routeKey identifies a route by method and path template, like "GET /health"
+func routeKey(method, route string) string {
+	return method + " " + route
+}
+
+// skipsAccess reports whether the route matched by a request is public or
+// transcoded, in which case the gRPC interceptors check access instead
+func (a *routeAccess) skipsAccess(method, route string) bool {
+	key := routeKey(method, route)
+	return a.publicRoutes[key] || a.gatewayRoutes[key]
+}
+
+// protect wraps next with the authentication and authorization middleware
+// that are enabled
+func (a *routeAccess) protect(next http.Handler) http.Handler {
+	if a.authz != nil {
+		next = a.authzMiddleware(next)
+	}
+	if a.auth != nil {
+		next = a.authMiddleware(next)
+	}
+	return next
+}
+
+// httpServer represents the HTTP server
+type httpServer struct {
+	routeAccess
+	server  *http.Server
+	config  *config.HTTPConfig
+	metrics *metrics.Metrics
+	tracing *tracing.Provider
+	listen  listenFunc
+	ready   atomic.Bool
+	// profile enables CORS and verbose errors
+	profile config.Profile
+	// container creates the request scopes, nil disables them
+	container *di.Container
+	// rateLimits bounds request rates and concurrency, nil disables it
+	rateLimits *RateLimits
 }
 
 type HttpServerParams struct {
-	services  *services.Services
-	logger    *logrus.Logger
-	serverEnv config.Environment
+	services *services.Services
+	logger   *logrus.Logger
+	config   *config.HTTPConfig
+	// profile enables CORS and verbose errors
+	profile config.Profile
+	// gateway registers routes transcoded to gRPC methods
+	gateway *gateway.Gateway
+	// admin registers the admin routes when they share the HTTP server
+	admin common.HttpHandler
+	// metrics instruments every route, nil disables it
+	metrics *metrics.Metrics
+	// tracing starts a span for every request, nil disables it
+	tracing *tracing.Provider
+	// listen overrides the listener, by default the configured port is bound
+	listen func() (net.Listener, error)
+	// h2c enables HTTP/2 over cleartext alongside HTTP/1.1
+	h2c bool
+	// tls serves HTTPS with the given configuration, nil serves plaintext
+	tls *tls.Config
+	// auth requires a bearer token on non-public routes, nil disables it
+	auth *auth.Authenticator
+	// authz enforces route policies on non-public routes, nil disables it
+	authz *authz.Engine
+	// rateLimits bounds request rates and concurrency, nil disables it
+	rateLimits *RateLimits
 }
 
 // NewHTTPServer creates a new HTTP server
 func NewHTTPServer(params HttpServerParams) *httpServer {
 	router := mux.NewRouter()
 	server := &httpServer{
-		router: router,
-		logger: params.logger,
+		routeAccess: routeAccess{
+			router: router,
+			logger: params.logger,
+			auth:   params.auth,
+			authz:  params.authz,
+		},
+		config:  params.config,
+		metrics: params.metrics,
+		tracing: params.tracing,
+		listen:  params.listen,
+		profile: params.profile,
+
+		container:  params.services.Container,
+		rateLimits: params.rateLimits,
+	}
+	if server.listen == nil {
+		server.listen = tcpListener("", params.config.Port)
+	}
+
+	// Add route matching, tracing, request logging, request scope, metrics,
+	// CORS, panic recovery, limiting, authentication and authorization
+	// middleware
+	var handler http.Handler = router
+	if params.authz != nil {
+		handler = server.authzMiddleware(handler)
+	}
+	if params.rateLimits != nil {
+		// Limits run after authentication so clients can be keyed by principal
+		handler = server.rateLimitMiddleware(handler)
+	}
+	if params.auth != nil {
+		handler = server.authMiddleware(handler)
+	}
+	if params.rateLimits != nil && params.rateLimits.inFlight != nil {
+		handler = server.concurrencyMiddleware(handler)
+	}
+	handler = panicRecoveryMiddleware(params.logger, params.profile.VerboseErrors, handler)
+	if params.profile.CORS.Enabled {
+		// Preflight requests are answered before authentication
+		handler = server.corsMiddleware(handler)
+	}
+	if params.metrics != nil {
+		handler = server.metricsMiddleware(handler)
 	}
+	if server.container != nil {
+		handler = server.scopeMiddleware(handler)
+	}
+	handler = requestLoggingMiddleware(params.logger, handler)
+	if params.tracing != nil {
+		handler = server.tracingMiddleware(handler)
+	}
+	if params.tls != nil {
+		handler = peerMiddleware(handler)
+	}
+	handler = routeMiddleware(router, handler)
+	if params.h2c {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	server.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", params.config.Port),
+		Handler:      handler,
+		ReadTimeout:  params.config.ReadTimeout,
+		WriteTimeout: params.config.WriteTimeout,
+		IdleTimeout:  params.config.IdleTimeout,
+		TLSConfig:    params.tls,
+	}
+
+	httpHandlers := httpHandlers.NewHttpHandlers(params.services)
+	httpHandlers.RegisterRoutes(router)
+	if params.admin != nil {
+		params.admin.RegisterRoutes(router)
+	}
+	if params.gateway != nil {
+		params.gateway.RegisterRoutes(router)
+	}
+
+	server.publicRoutes = map[string]bool{}
+	for _, route := range common.PublicRoutes(httpHandlers, params.admin) {
+		server.publicRoutes[route] = true
+	}
+	server.gatewayRoutes = map[string]bool{}
+	if params.gateway != nil {
+		for _, route := range params.gateway.Routes() {
+			server.gatewayRoutes[route] = true
+		}
+	}
+
 	return server
 }
 
-// panicRecoveryMiddleware recovers from panics and logs the error
-func (s *httpServer) panicRecoveryMiddleware(next http.Handler) http.Handler {
+// panicRecoveryMiddleware recovers from panics and logs the error, verbose
+// errors include the panic in the response
+func panicRecoveryMiddleware(logger *logrus.Logger, verboseErrors bool, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				s.logger.Errorf("Recovered from panic in HTTP handler: %v\nStack trace:\n%s", err, debug.Stack())
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				logging.FromContext(r.Context(), logger).Errorf("Recovered from panic in HTTP handler: %v\nStack trace:\n%s", err, debug.Stack())
+				message := "Internal Server Error"
+				if verboseErrors {
+					message = fmt.Sprintf("Internal Server Error: %v", err)
+				}
+				http.Error(w, message, http.StatusInternalServerError)
 			}
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
 
-// Start starts the HTTP server
-func (s *httpServer) Start(config *config.HTTPConfig) error {
-	// Add panic recovery middleware
-	handler := s.panicRecoveryMiddleware(s.router)
+// Name returns the component name
+func (s *httpServer) Name() string {
+	return "http server"
+}
 
-	s.server = &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.Port),
-		Handler:      handler,
-		ReadTimeout:  config.ReadTimeout,
-		WriteTimeout: config.WriteTimeout,
-		IdleTimeout:  config.IdleTimeout,
+// Start starts the HTTP server and blocks until it is stopped
+func (s *httpServer) Start(ctx context.Context) error {
+	lis, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
 	}
 
-	httpHandlers := httpHandlers.NewHttpHandlers(s.server)
-	httpHandlers.RegisterRoutes(s.router)
+	s.logger.Infof("Starting HTTP server on %s", lis.Addr())
+	s.ready.Store(true)
+	defer s.ready.Store(false)
 
-	return s.server.ListenAndServe()
+	// Certificates are provided by TLSConfig, so no files are passed to ServeTLS
+	serve := s.server.Serve
+	if s.server.TLSConfig != nil {
+		serve = func(lis net.Listener) error {
+			return s.server.ServeTLS(lis, "", "")
+		}
+	}
+
+	if err := serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+
+	return nil
 }
 
-// Shutdown gracefully shuts down the HTTP server
-func (s *httpServer) Shutdown(ctx context.Context) error {
+// Stop gracefully shuts down the HTTP server
+func (s *httpServer) Stop(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
+
+// Ready reports whether the HTTP server is accepting connections
+func (s *httpServer) Ready() bool {
+	return s.ready.Load()
+}
@@ -0,0 +1,389 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/authz"
+	"github.com/Gambitier/voidkitgo/internal/certs"
+	"github.com/Gambitier/voidkitgo/internal/di"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/Gambitier/voidkitgo/internal/metrics"
+	"github.com/Gambitier/voidkitgo/internal/tracing"
+	"github.com/Gambitier/voidkitgo/pkg/proto/common"
+	"github.com/sirupsen/logrus"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// wrappedServerStream overrides the context of a stream and counts the bytes sent
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	bytes int
+}
+
+func (w *wrappedServerStream) Context() context.Context {
+	return w.ctx
+}
+
+func (w *wrappedServerStream) SendMsg(m interface{}) error {
+	if msg, ok := m.(proto.Message); ok {
+		w.bytes += proto.Size(msg)
+	}
+	return w.ServerStream.SendMsg(m)
+}
+
+// incomingRequestID returns the request ID sent by the caller, the one already
+// assigned by the HTTP gateway, or a new one
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(logging.RequestIDMetadataKey); len(values) > 0 && logging.ValidRequestID(values[0]) {
+			return values[0]
+		}
+	}
+	if requestID := logging.RequestIDFromContext(ctx); requestID != "" {
+		return requestID
+	}
+	return logging.NewRequestID()
+}
+
+// withRequestLogger attaches the request ID and a request-scoped entry to ctx
+func withRequestLogger(ctx context.Context, logger *logrus.Logger, fullMethod string) (context.Context, *logrus.Entry, string) {
+	requestID := incomingRequestID(ctx)
+	entry := logger.WithFields(logrus.Fields{
+		"request_id":  requestID,
+		"grpc_method": fullMethod,
+	}).WithFields(tracing.LogFields(ctx))
+
+	ctx = logging.WithRequestID(ctx, requestID)
+	ctx = logging.WithEntry(ctx, entry)
+	return ctx, entry, requestID
+}
+
+// logGrpcAccess writes the access log line of a finished call
+func logGrpcAccess(entry *logrus.Entry, start time.Time, err error, bytes int) {
+	code := status.Code(err)
+	accessEntry := entry.WithFields(logrus.Fields{
+		"grpc_code":  code.String(),
+		"bytes":      bytes,
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+	})
+
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		accessEntry.WithError(err).Error("gRPC request")
+	default:
+		accessEntry.Info("gRPC request")
+	}
+}
+
+// requestLoggingUnaryInterceptor assigns or propagates the request ID, attaches
+// a request-scoped log entry to the context and writes one access log line per call
+func requestLoggingUnaryInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, entry, requestID := withRequestLogger(ctx, logger, info.FullMethod)
+		grpc.SetHeader(ctx, metadata.Pairs(logging.RequestIDMetadataKey, requestID))
+
+		resp, err := handler(ctx, req)
+
+		bytes := 0
+		if msg, ok := resp.(proto.Message); ok && err == nil {
+			bytes = proto.Size(msg)
+		}
+		logGrpcAccess(entry, start, err, bytes)
+
+		return resp, err
+	}
+}
+
+// requestLoggingStreamInterceptor is the stream counterpart of requestLoggingUnaryInterceptor
+func requestLoggingStreamInterceptor(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, entry, requestID := withRequestLogger(stream.Context(), logger, info.FullMethod)
+		stream.SetHeader(metadata.Pairs(logging.RequestIDMetadataKey, requestID))
+
+		wrapped := &wrappedServerStream{ServerStream: stream, ctx: ctx}
+		err := handler(srv, wrapped)
+		logGrpcAccess(entry, start, err, wrapped.bytes)
+
+		return err
+	}
+}
+
+// metricsUnaryInterceptor records call counts, latencies and in-flight calls
+func metricsUnaryInterceptor(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		done := m.GRPCStarted(info.FullMethod, metrics.Unary)
+
+		resp, err := handler(ctx, req)
+		done(status.Code(err), time.Since(start))
+
+		return resp, err
+	}
+}
+
+// metricsStreamInterceptor is the stream counterpart of metricsUnaryInterceptor
+func metricsStreamInterceptor(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		done := m.GRPCStarted(info.FullMethod, metrics.StreamType(info.IsClientStream, info.IsServerStream))
+
+		err := handler(srv, stream)
+		done(status.Code(err), time.Since(start))
+
+		return err
+	}
+}
+
+// startServerSpan starts the server span of a call, continuing the span already
+// in ctx (calls through the HTTP gateway) or the trace of the incoming metadata
+func startServerSpan(ctx context.Context, p *tracing.Provider, fullMethod string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = p.Propagator().Extract(ctx, tracing.MetadataCarrier(md))
+		}
+	}
+
+	service, method := splitFullMethod(fullMethod)
+	return p.Tracer().Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(method),
+		),
+	)
+}
+
+// endServerSpan records the status of the call and ends the span
+func endServerSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+// splitFullMethod splits "/package.Service/Method" into service and method
+func splitFullMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", service
+	}
+	return service, method
+}
+
+// tracingUnaryInterceptor starts a server span for every call
+func tracingUnaryInterceptor(p *tracing.Provider) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startServerSpan(ctx, p, info.FullMethod)
+		resp, err := handler(ctx, req)
+		endServerSpan(span, err)
+		return resp, err
+	}
+}
+
+// tracingStreamInterceptor is the stream counterpart of tracingUnaryInterceptor
+func tracingStreamInterceptor(p *tracing.Provider) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startServerSpan(stream.Context(), p, info.FullMethod)
+		err := handler(srv, &wrappedServerStream{ServerStream: stream, ctx: ctx})
+		endServerSpan(span, err)
+		return err
+	}
+}
+
+// withPeer attaches the client certificate identity of the connection to ctx.
+// Calls transcoded by the gateway already carry the identity of the HTTP connection.
+func withPeer(ctx context.Context) context.Context {
+	if _, ok := certs.PeerFromContext(ctx); ok {
+		return ctx
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ctx
+	}
+	if identity := certs.PeerFromConnectionState(&tlsInfo.State); identity != nil {
+		return certs.WithPeer(ctx, identity)
+	}
+	return ctx
+}
+
+// peerUnaryInterceptor exposes the mTLS peer identity to handlers through certs.PeerFromContext
+func peerUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withPeer(ctx), req)
+	}
+}
+
+// peerStreamInterceptor is the stream counterpart of peerUnaryInterceptor
+func peerStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: withPeer(stream.Context())})
+	}
+}
+
+// authenticate verifies the bearer token of the authorization metadata and
+// attaches the principal to ctx
+func authenticate(ctx context.Context, a *auth.Authenticator, logger *logrus.Logger) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = auth.BearerToken(values[0])
+		}
+	}
+
+	principal, err := a.Authenticate(ctx, token)
+	if err != nil {
+		logging.FromContext(ctx, logger).Debugf("Authentication failed: %v", err)
+		return ctx, status.Error(codes.Unauthenticated, unauthenticatedMessage(err))
+	}
+
+	ctx = auth.WithPrincipal(ctx, principal)
+	return logging.WithEntry(ctx, logging.FromContext(ctx, logger).WithField("subject", principal.Subject)), nil
+}
+
+// authUnaryInterceptor requires a valid bearer token on every method that is not public
+func authUnaryInterceptor(a *auth.Authenticator, public map[string]bool, logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, a, logger)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStreamInterceptor is the stream counterpart of authUnaryInterceptor
+func authStreamInterceptor(a *auth.Authenticator, public map[string]bool, logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public[info.FullMethod] {
+			return handler(srv, stream)
+		}
+		ctx, err := authenticate(stream.Context(), a, logger)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// permissionDenied converts an authorization failure into a PermissionDenied
+// status carrying an ERROR_PERMISSION_DENIED detail, the reason is only audit logged
+func permissionDenied() error {
+	st := status.New(codes.PermissionDenied, authz.ErrPermissionDenied.Error())
+	detailed, err := st.WithDetails(&common.Error{
+		Code:    common.ErrorCode_ERROR_PERMISSION_DENIED,
+		Message: authz.ErrPermissionDenied.Error(),
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// authzUnaryInterceptor enforces the policy of every method that is not public
+func authzUnaryInterceptor(engine *authz.Engine, public map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !public[info.FullMethod] {
+			if err := engine.AuthorizeMethod(ctx, info.FullMethod); err != nil {
+				return nil, permissionDenied()
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authzStreamInterceptor is the stream counterpart of authzUnaryInterceptor
+func authzStreamInterceptor(engine *authz.Engine, public map[string]bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !public[info.FullMethod] {
+			if err := engine.AuthorizeMethod(stream.Context(), info.FullMethod); err != nil {
+				return permissionDenied()
+			}
+		}
+		return handler(srv, stream)
+	}
+}
+
+// hideInternalError replaces the message of internal errors, which may
+// describe implementation details, with a generic one
+func hideInternalError(err error) error {
+	switch code := status.Code(err); code {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		return status.Error(code, "Internal server error")
+	}
+	return err
+}
+
+// hideInternalErrorsUnaryInterceptor hides the message of internal errors from
+// callers, it runs first so logs and traces keep the original message
+func hideInternalErrorsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, hideInternalError(err)
+	}
+}
+
+// hideInternalErrorsStreamInterceptor is the stream counterpart of hideInternalErrorsUnaryInterceptor
+func hideInternalErrorsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return hideInternalError(handler(srv, stream))
+	}
+}
+
+// closeScope closes the scope of a request, logging instances that failed to close
+func closeScope(ctx context.Context, scope *di.Container, logger *logrus.Logger) {
+	if err := scope.Close(); err != nil {
+		logging.FromContext(ctx, logger).Errorf("Failed to close request scope: %v", err)
+	}
+}
+
+// scopeUnaryInterceptor runs every call in a scope of the service container,
+// which handlers reach through di.ScopeFromContext. Transcoded calls keep the
+// scope created by the HTTP server.
+func scopeUnaryInterceptor(container *di.Container, logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if di.ScopeFromContext(ctx) != nil {
+			return handler(ctx, req)
+		}
+		scope := container.Scope()
+		defer closeScope(ctx, scope, logger)
+		return handler(di.WithScope(ctx, scope), req)
+	}
+}
+
+// scopeStreamInterceptor is the stream counterpart of scopeUnaryInterceptor
+func scopeStreamInterceptor(container *di.Container, logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if di.ScopeFromContext(stream.Context()) != nil {
+			return handler(srv, stream)
+		}
+		scope := container.Scope()
+		defer closeScope(stream.Context(), scope, logger)
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: di.WithScope(stream.Context(), scope)})
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/internal/di"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// contextStream is a server stream carrying ctx
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestScopeInterceptors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	container := di.New()
+	existing := container.Scope()
+	defer existing.Close()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		// want reports whether the handler got the expected scope
+		want func(scope *di.Container) bool
+	}{
+		{
+			name: "new scope",
+			ctx:  context.Background(),
+			want: func(scope *di.Container) bool { return scope != nil && scope != existing },
+		},
+		{
+			// Transcoded calls keep the scope of the HTTP request
+			name: "existing scope",
+			ctx:  di.WithScope(context.Background(), existing),
+			want: func(scope *di.Container) bool { return scope == existing },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unary := scopeUnaryInterceptor(container, logger)
+			unary(tt.ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				if scope := di.ScopeFromContext(ctx); !tt.want(scope) {
+					t.Errorf("unary handler scope = %p, existing %p", scope, existing)
+				}
+				return nil, nil
+			})
+
+			stream := scopeStreamInterceptor(container, logger)
+			stream(nil, contextStream{ctx: tt.ctx}, &grpc.StreamServerInfo{}, func(srv any, stream grpc.ServerStream) error {
+				if scope := di.ScopeFromContext(stream.Context()); !tt.want(scope) {
+					t.Errorf("stream handler scope = %p, existing %p", scope, existing)
+				}
+				return nil
+			})
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestIncomingRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "metadata",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs(logging.RequestIDMetadataKey, "from-caller")),
+			want: "from-caller",
+		},
+		{
+			// Transcoded calls keep the ID assigned by the HTTP server
+			name: "context",
+			ctx:  logging.WithRequestID(context.Background(), "from-gateway"),
+			want: "from-gateway",
+		},
+		{
+			name: "invalid metadata",
+			ctx: logging.WithRequestID(
+				metadata.NewIncomingContext(context.Background(), metadata.Pairs(logging.RequestIDMetadataKey, "bad id")),
+				"from-gateway"),
+			want: "from-gateway",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := incomingRequestID(tt.ctx); got != tt.want {
+				t.Errorf("incomingRequestID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := incomingRequestID(context.Background()); !logging.ValidRequestID(got) {
+		t.Errorf("incomingRequestID() without one = %q, want a new ID", got)
+	}
+}
+
+func TestRequestLoggingUnaryInterceptor(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetOutput(io.Discard)
+	interceptor := requestLoggingUnaryInterceptor(logger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantLevel logrus.Level
+	}{
+		{name: "ok", wantLevel: logrus.InfoLevel},
+		{name: "client error", err: status.Error(codes.NotFound, "missing"), wantLevel: logrus.InfoLevel},
+		{name: "server error", err: status.Error(codes.Internal, "boom"), wantLevel: logrus.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(logging.RequestIDMetadataKey, "req-1"))
+			interceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+				// Handlers log through the request entry
+				logging.FromContext(ctx, nil).Info("handling")
+				return nil, tt.err
+			})
+
+			entries := hook.AllEntries()
+			if len(entries) != 2 {
+				t.Fatalf("logged %d entries, want the handler and access entries", len(entries))
+			}
+			for _, entry := range entries {
+				if entry.Data["request_id"] != "req-1" || entry.Data["grpc_method"] != info.FullMethod {
+					t.Errorf("entry %q fields = %v, want the request ID and method", entry.Message, entry.Data)
+				}
+			}
+			access := entries[1]
+			if access.Level != tt.wantLevel || access.Data["grpc_code"] != status.Code(tt.err).String() {
+				t.Errorf("access entry = %s %v, want %s with the status code", access.Level, access.Data, tt.wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/certs"
+	"github.com/Gambitier/voidkitgo/internal/di"
+	"github.com/Gambitier/voidkitgo/internal/logging"
+	"github.com/Gambitier/voidkitgo/internal/server/gateway"
+	"github.com/Gambitier/voidkitgo/internal/tracing"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// unmatchedRoute is the route logged for requests no route matched
+const unmatchedRoute = "unmatched"
+
+// routeKey is the context key of the path template of the matched route
+type routeCtxKey struct{}
+
+// responseRecorder captures the status code and body size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher for streaming handlers
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// routeTemplate returns the path template of the route matching r
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return unmatchedRoute
+	}
+	if tmpl, err := match.Route.GetPathTemplate(); err == nil {
+		return tmpl
+	}
+	return unmatchedRoute
+}
+
+// routeMiddleware matches every request against router once, ahead of the
+// other middleware, which read the path template with requestRoute
+func routeMiddleware(router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), routeCtxKey{}, routeTemplate(router, r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestRoute returns the path template of the route matching r, as found
+// by routeMiddleware
+func requestRoute(r *http.Request) string {
+	if route, ok := r.Context().Value(routeCtxKey{}).(string); ok {
+		return route
+	}
+	return unmatchedRoute
+}
+
+// requestLoggingMiddleware assigns or propagates the request ID, attaches a
+// request-scoped log entry to the context and writes one access log line per request
+func requestLoggingMiddleware(logger *logrus.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(logging.RequestIDHeader)
+		if !logging.ValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(logging.RequestIDHeader, requestID)
+
+		route := requestRoute(r)
+		entry := logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"method":     r.Method,
+			"route":      route,
+		}).WithFields(tracing.LogFields(r.Context()))
+
+		ctx := logging.WithRequestID(r.Context(), requestID)
+		ctx = logging.WithEntry(ctx, entry)
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		accessEntry := entry.WithFields(logrus.Fields{
+			"path":        r.URL.Path,
+			"status":      recorder.status,
+			"bytes":       recorder.bytes,
+			"latency_ms":  float64(time.Since(start).Microseconds()) / 1000,
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		})
+		if recorder.status >= http.StatusInternalServerError {
+			accessEntry.Error("HTTP request")
+		} else {
+			accessEntry.Info("HTTP request")
+		}
+	})
+}
+
+// metricsMiddleware records request counts, latencies and in-flight requests
+// labelled by route template rather than raw path to bound cardinality
+func (s *httpServer) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		done := s.metrics.HTTPStarted()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		done(r.Method, requestRoute(r), recorder.status, time.Since(start))
+	})
+}
+
+// tracingMiddleware starts a server span for every request, continuing the
+// trace of the incoming traceparent header and carrying its baggage
+func (s *httpServer) tracingMiddleware(next http.Handler) http.Handler {
+	tracer, propagator := s.tracing.Tracer(), s.tracing.Propagator()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := requestRoute(r)
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				semconv.ClientAddress(r.RemoteAddr),
+			),
+		)
+		defer span.End()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// peerMiddleware exposes the verified client certificate identity of mTLS
+// connections to handlers through certs.PeerFromContext
+func peerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := certs.PeerFromConnectionState(r.TLS); p != nil {
+			r = r.WithContext(certs.WithPeer(r.Context(), p))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// scopeMiddleware runs every request in a scope of the service container,
+// which handlers reach through di.ScopeFromContext
+func (s *httpServer) scopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := s.container.Scope()
+		defer closeScope(r.Context(), scope, s.logger)
+		next.ServeHTTP(w, r.WithContext(di.WithScope(r.Context(), scope)))
+	})
+}
+
+// defaultCORSMethods are the methods allowed across origins when none are configured
+var defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// corsMiddleware applies the CORS policy of the environment profile. Requests
+// from origins that are not allowed get no CORS headers, so browsers block
+// their responses, and preflight requests are answered without reaching the
+// routes.
+func (s *httpServer) corsMiddleware(next http.Handler) http.Handler {
+	cfg := s.profile.CORS
+	anyOrigin := false
+	origins := map[string]bool{}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		origins[strings.ToLower(origin)] = true
+	}
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowedMethods := strings.Join(methods, ", ")
+	allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		if origin == "" || !anyOrigin && !origins[strings.ToLower(origin)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Credentials cannot be allowed along with the * wildcard
+		if anyOrigin && !cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			header.Set("Access-Control-Expose-Headers", logging.RequestIDHeader)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowedMethods)
+		if allowedHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowedHeaders)
+		} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if cfg.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// authMiddleware requires a valid bearer token on every matched route that is
+// not public and attaches the authenticated principal to the context.
+// Transcoded calls are authenticated by the gRPC interceptors instead, so
+// public methods stay public over HTTP.
+func (s *routeAccess) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := requestRoute(r)
+		if route == unmatchedRoute || s.skipsAccess(r.Method, route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := auth.BearerToken(r.Header.Get("Authorization"))
+		principal, err := s.auth.Authenticate(r.Context(), token)
+		if err != nil {
+			logging.FromContext(r.Context(), s.logger).Debugf("Authentication failed: %v", err)
+			challenge := "Bearer"
+			if !errors.Is(err, auth.ErrMissingToken) {
+				challenge = `Bearer error="invalid_token"`
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": unauthenticatedMessage(err)})
+			return
+		}
+
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		ctx = logging.WithEntry(ctx, logging.FromContext(ctx, s.logger).WithField("subject", principal.Subject))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// unauthenticatedMessage returns the message sent to callers failing
+// authentication, without the verification details
+func unauthenticatedMessage(err error) string {
+	if errors.Is(err, auth.ErrMissingToken) {
+		return auth.ErrMissingToken.Error()
+	}
+	return auth.ErrInvalidToken.Error()
+}
+
+// authzMiddleware enforces the policy of every matched route that is not public,
+// denials are written in the same format as transcoded gRPC errors. Transcoded
+// calls are authorized by the gRPC interceptors instead.
+func (s *routeAccess) authzMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := requestRoute(r)
+		if route != unmatchedRoute && !s.skipsAccess(r.Method, route) {
+			if err := s.authz.AuthorizeRoute(r.Context(), r.Method, route); err != nil {
+				gateway.WriteError(w, permissionDenied())
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		cors        config.CORSConfig
+		method      string
+		headers     map[string]string
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{
+			name:        "no origin",
+			cors:        config.CORSConfig{AllowedOrigins: []string{"https://app.example"}},
+			method:      http.MethodGet,
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:        "disallowed origin",
+			cors:        config.CORSConfig{AllowedOrigins: []string{"https://app.example"}},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://evil.example"},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:       "simple request",
+			cors:       config.CORSConfig{AllowedOrigins: []string{"https://app.example"}},
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://APP.example"},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://APP.example",
+				"Access-Control-Expose-Headers": "X-Request-ID",
+				"Access-Control-Allow-Methods":  "",
+			},
+		},
+		{
+			name:   "preflight",
+			cors:   config.CORSConfig{AllowedOrigins: []string{"https://app.example"}, MaxAge: time.Hour},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example",
+				"Access-Control-Request-Method":  "DELETE",
+				"Access-Control-Request-Headers": "Authorization, Content-Type",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers": "Authorization, Content-Type",
+				"Access-Control-Max-Age":       "3600",
+			},
+		},
+		{
+			name: "preflight with configured headers",
+			cors: config.CORSConfig{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET"},
+				AllowedHeaders: []string{"Authorization"},
+			},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET",
+				"Access-Control-Allow-Headers": "Authorization",
+				"Access-Control-Max-Age":       "",
+			},
+		},
+		{
+			// Plain OPTIONS requests are not preflights and reach the handler
+			name:        "options without request method",
+			cors:        config.CORSConfig{AllowedOrigins: []string{"*"}},
+			method:      http.MethodOptions,
+			headers:     map[string]string{"Origin": "https://app.example"},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+		{
+			// Credentials cannot be allowed with the wildcard, the origin is echoed
+			name:       "wildcard with credentials",
+			cors:       config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://app.example"},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &httpServer{profile: config.Profile{CORS: tt.cors}}
+			handler := s.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(tt.method, "/v1/items", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			for key, want := range tt.wantHeaders {
+				if got := w.Header().Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			// Responses depend on the origin, caches must not share them
+			if vary := w.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Origin" {
+				t.Errorf("Vary = %v, want Origin first", vary)
+			}
+		})
+	}
+}
+
+func TestCORSPreflightVary(t *testing.T) {
+	s := &httpServer{profile: config.Profile{CORS: config.CORSConfig{AllowedOrigins: []string{"*"}}}}
+	handler := s.corsMiddleware(http.NotFoundHandler())
+
+	r := httptest.NewRequest(http.MethodOptions, "/v1/items", nil)
+	r.Header.Set("Origin", "https://app.example")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	want := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+	if got := w.Header().Values("Vary"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Vary = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soheilhy/cmux"
+)
+
+// listenFunc creates the listener a server accepts connections on
+type listenFunc func() (net.Listener, error)
+
+// tcpListener returns a listenFunc binding the given TCP port on host, or on
+// every interface when host is empty
+func tcpListener(host string, port int) listenFunc {
+	return func() (net.Listener, error) {
+		return net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+}
+
+// isLoopback reports whether a listener bound to host only accepts local
+// connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// muxServer accepts connections on a single port and routes them to the gRPC
+// or HTTP server by inspecting the connection preface: HTTP/2 streams with a
+// gRPC content type go to gRPC, everything else (HTTP/1.1 and h2c) to HTTP
+type muxServer struct {
+	port   int
+	logger *logrus.Logger
+	root   net.Listener
+	mux    cmux.CMux
+	grpc   net.Listener
+	http   net.Listener
+	ready  atomic.Bool
+}
+
+// newMuxServer creates a connection multiplexer for the given port
+func newMuxServer(port int, logger *logrus.Logger) *muxServer {
+	return &muxServer{
+		port:   port,
+		logger: logger,
+	}
+}
+
+// Name returns the component name
+func (m *muxServer) Name() string {
+	return "listener multiplexer"
+}
+
+// Start binds the shared port and blocks while dispatching connections
+func (m *muxServer) Start(ctx context.Context) error {
+	root, err := net.Listen("tcp", fmt.Sprintf(":%d", m.port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	m.root = root
+	m.mux = cmux.New(root)
+	// Matchers are evaluated in order, so gRPC must be matched before the catch-all
+	m.grpc = m.mux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc"))
+	m.http = m.mux.Match(cmux.Any())
+
+	m.logger.Infof("Starting HTTP and gRPC servers on shared port %d", m.port)
+	m.ready.Store(true)
+	defer m.ready.Store(false)
+
+	if err := m.mux.Serve(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+
+	return nil
+}
+
+// Stop closes the shared listener, the HTTP and gRPC servers are expected to
+// have drained their connections already
+func (m *muxServer) Stop(ctx context.Context) error {
+	if m.root == nil {
+		return nil
+	}
+	m.mux.Close()
+	if err := m.root.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// Ready reports whether the shared port is bound
+func (m *muxServer) Ready() bool {
+	return m.ready.Load()
+}
+
+// grpcListener returns a listenFunc for connections routed to gRPC
+func (m *muxServer) grpcListener() listenFunc {
+	return func() (net.Listener, error) {
+		if !m.Ready() {
+			return nil, errors.New("listener multiplexer is not started")
+		}
+		return m.grpc, nil
+	}
+}
+
+// httpListener returns a listenFunc for connections routed to HTTP
+func (m *muxServer) httpListener() listenFunc {
+	return func() (net.Listener, error) {
+		if !m.Ready() {
+			return nil, errors.New("listener multiplexer is not started")
+		}
+		return m.http, nil
+	}
+}
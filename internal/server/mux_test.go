@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestMuxServerRoutesByProtocol(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m := newMuxServer(0, logger)
+
+	// The listeners are only available once the shared port is bound
+	if _, err := m.httpListener()(); err == nil {
+		t.Errorf("httpListener() before Start error = nil")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- m.Start(context.Background()) }()
+	for deadline := time.Now().Add(5 * time.Second); !m.Ready(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("listener multiplexer not ready")
+		}
+	}
+	addr := m.root.Addr().String()
+
+	grpcListener, err := m.grpcListener()()
+	if err != nil {
+		t.Fatalf("grpcListener() error = %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	go grpcServer.Serve(grpcListener)
+	defer grpcServer.Stop()
+
+	httpListener, err := m.httpListener()()
+	if err != nil {
+		t.Fatalf("httpListener() error = %v", err)
+	}
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "http")
+	})}
+	go httpServer.Serve(httpListener)
+	defer httpServer.Close()
+
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "http" {
+		t.Errorf("GET = %q, want the HTTP server", body)
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	check, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || check.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Check() = %v, %v, want SERVING from the gRPC server", check, err)
+	}
+
+	if err := m.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	if _, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		t.Errorf("shared port still accepts connections after Stop()")
+	}
+}
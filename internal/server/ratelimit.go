@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/ratelimit"
+	"github.com/Gambitier/voidkitgo/internal/server/gateway"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// retryAfterConcurrency is the delay suggested to callers rejected because
+// too many requests are in flight
+const retryAfterConcurrency = 1
+
+// RateLimits applies the rate and concurrency limits shared by both servers
+type RateLimits struct {
+	config  *config.RateLimitConfig
+	limiter *ratelimit.Limiter
+	// inFlight is nil when concurrency is unlimited
+	inFlight *ratelimit.ConcurrencyLimiter
+}
+
+// update applies reloaded rates, bursts and overrides to new requests
+func (l *RateLimits) update(cfg *config.RateLimitConfig) error {
+	rules, err := ratelimit.NewRules(cfg)
+	if err != nil {
+		return err
+	}
+	l.limiter.SetRules(rules)
+	return nil
+}
+
+// clientKey identifies the client owning a bucket, falling back to its IP
+// when it has no principal or API key
+func (l *RateLimits) clientKey(ctx context.Context, ip, apiKey string) string {
+	switch l.config.Key {
+	case "principal":
+		if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Subject != "" {
+			return "principal:" + principal.Subject
+		}
+	case "api_key":
+		if apiKey != "" {
+			// API keys are secrets, they are never stored as is
+			sum := sha256.Sum256([]byte(apiKey))
+			return "api_key:" + hex.EncodeToString(sum[:16])
+		}
+	}
+	return "ip:" + ip
+}
+
+// forwardedFor returns the first address of an X-Forwarded-For value
+func forwardedFor(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// requestIP returns the client address of an HTTP request
+func (l *RateLimits) requestIP(r *http.Request) string {
+	if l.config.TrustForwardedFor {
+		if ip := forwardedFor(r.Header.Get("X-Forwarded-For")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// callIP returns the client address of a gRPC call
+func (l *RateLimits) callIP(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if l.config.TrustForwardedFor {
+		if values := md.Get("x-forwarded-for"); len(values) > 0 {
+			if ip := forwardedFor(values[0]); ip != "" {
+				return ip
+			}
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// callAPIKey returns the API key metadata of a gRPC call
+func (l *RateLimits) callAPIKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(strings.ToLower(l.config.APIKeyHeader)); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// resourceExhausted builds the status returned to limited callers, carrying a
+// RetryInfo detail the gateway turns into a Retry-After header
+func resourceExhausted(message string, retryAfterSeconds int) error {
+	st := status.New(codes.ResourceExhausted, message)
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(time.Duration(retryAfterSeconds) * time.Second),
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// writeLimited writes a 429 response in the same format as transcoded gRPC errors
+func writeLimited(w http.ResponseWriter, message string, retryAfterSeconds int) {
+	gateway.WriteError(w, resourceExhausted(message, retryAfterSeconds))
+}
+
+// concurrencyMiddleware rejects requests once too many are in flight.
+// Transcoded calls are limited by the gRPC interceptors instead.
+func (s *httpServer) concurrencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.gatewayRoutes[routeKey(r.Method, requestRoute(r))] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.rateLimits.inFlight.TryAcquire() {
+			writeLimited(w, "too many requests in flight", retryAfterConcurrency)
+			return
+		}
+		defer s.rateLimits.inFlight.Release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitMiddleware takes a token from the bucket of the client for every
+// matched route. Transcoded calls are limited by the gRPC interceptors instead.
+func (s *httpServer) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := requestRoute(r)
+		if route == unmatchedRoute || s.gatewayRoutes[routeKey(r.Method, route)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limits := s.rateLimits
+		client := limits.clientKey(r.Context(), limits.requestIP(r), r.Header.Get(limits.config.APIKeyHeader))
+		result := limits.limiter.AllowRoute(r.Context(), r.Method, route, client)
+		if !result.Allowed {
+			writeLimited(w, "rate limit exceeded", ratelimit.RetryAfterSeconds(result.RetryAfter))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// concurrencyUnaryInterceptor rejects calls once too many are in flight
+func concurrencyUnaryInterceptor(limits *RateLimits) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !limits.inFlight.TryAcquire() {
+			return nil, limitedCall(ctx, "too many requests in flight", retryAfterConcurrency)
+		}
+		defer limits.inFlight.Release()
+		return handler(ctx, req)
+	}
+}
+
+// concurrencyStreamInterceptor is the stream counterpart of concurrencyUnaryInterceptor
+func concurrencyStreamInterceptor(limits *RateLimits) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !limits.inFlight.TryAcquire() {
+			return limitedCall(stream.Context(), "too many requests in flight", retryAfterConcurrency)
+		}
+		defer limits.inFlight.Release()
+		return handler(srv, stream)
+	}
+}
+
+// rateLimitUnaryInterceptor takes a token from the bucket of the client for every call
+func rateLimitUnaryInterceptor(limits *RateLimits) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := limits.allowCall(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitStreamInterceptor takes a token when a stream is opened
+func rateLimitStreamInterceptor(limits *RateLimits) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limits.allowCall(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func (l *RateLimits) allowCall(ctx context.Context, fullMethod string) error {
+	client := l.clientKey(ctx, l.callIP(ctx), l.callAPIKey(ctx))
+	result := l.limiter.AllowMethod(ctx, fullMethod, client)
+	if !result.Allowed {
+		return limitedCall(ctx, "rate limit exceeded", ratelimit.RetryAfterSeconds(result.RetryAfter))
+	}
+	return nil
+}
+
+// limitedCall sets the retry-after header metadata and returns the
+// ResourceExhausted status of a limited call
+func limitedCall(ctx context.Context, message string, retryAfterSeconds int) error {
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfterSeconds)))
+	return resourceExhausted(message, retryAfterSeconds)
+}
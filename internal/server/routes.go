@@ -0,0 +1,148 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/server/gateway"
+	httpHandlers "github.com/Gambitier/voidkitgo/internal/server/handlers/http"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/admin"
+	"github.com/Gambitier/voidkitgo/internal/services"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Route is an HTTP route or a gRPC method served by the server
+type Route struct {
+	// Server is the server exposing the route: http, admin or grpc
+	Server string
+	// Method is the HTTP method, empty for gRPC methods and routes matching
+	// any method
+	Method string
+	// Path is the HTTP path template or the full gRPC method name
+	Path string
+	// Target is the gRPC method an HTTP route is transcoded to, if any
+	Target string
+	// Public reports whether the route is reachable without authentication
+	Public bool
+}
+
+// Routes lists the routes the server registers with cfg. The servers are built
+// without starting them, and no dependency such as Redis or the database is
+// contacted.
+func Routes(cfg *config.Config, logger *logrus.Logger) ([]Route, error) {
+	services, err := services.NewServices(services.ServicesParams{Cache: &cfg.Cache})
+	if err != nil {
+		return nil, err
+	}
+
+	grpcServer := NewGrpcServer(GrpcServerParams{
+		Services: services,
+		Logger:   logger,
+		Config:   &cfg.Server.GRPC,
+		Profile:  cfg.Profile(),
+	}).(*grpcServer)
+
+	gw := gateway.NewGateway(gateway.GatewayParams{
+		Logger:       logger,
+		Interceptor:  grpcServer.UnaryInterceptor(),
+		MaxBodyBytes: cfg.Server.HTTP.MaxBodyBytes,
+	})
+	grpcServer.RegisterServices(gw)
+
+	// Only the presence of the metrics handler matters for its route
+	var metricsHandler http.Handler
+	if cfg.Server.Metrics.Enabled {
+		metricsHandler = http.NotFoundHandler()
+	}
+	adminParams := admin.AdminHandlerParams{
+		Logger:      logger,
+		Metrics:     metricsHandler,
+		MetricsPath: cfg.Server.Metrics.Path,
+		Pprof:       cfg.Profile().Pprof,
+	}
+	if cfg.Profile().DebugEndpoints {
+		adminParams.Config = cfg.Redacted
+	}
+	if unprotectedAdmin(cfg) {
+		restrictAdmin(&adminParams)
+	}
+	adminHandlers := httpHandlers.NewAdminHttpHandlers(adminParams)
+
+	httpParams := HttpServerParams{
+		services: services,
+		logger:   logger,
+		config:   &cfg.Server.HTTP,
+		profile:  cfg.Profile(),
+		gateway:  gw,
+	}
+	var adminServer *adminServer
+	if cfg.Server.Admin.Port > 0 {
+		adminServer = newAdminServer(adminServerParams{
+			config:   &cfg.Server.Admin,
+			http:     &cfg.Server.HTTP,
+			handlers: adminHandlers,
+			logger:   logger,
+		})
+	} else {
+		httpParams.admin = adminHandlers
+	}
+	httpServer := NewHTTPServer(httpParams)
+
+	// Gateway routes are public when the method they call is
+	targets := map[string]string{}
+	for _, binding := range gw.Bindings() {
+		targets[routeKey(binding.HTTPMethod, binding.Path)] = binding.FullMethod
+	}
+
+	var routes []Route
+	walk := func(server string, access *routeAccess) error {
+		return access.router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				return nil
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				methods = []string{""}
+			}
+			for _, method := range methods {
+				target := targets[routeKey(method, path)]
+				routes = append(routes, Route{
+					Server: server,
+					Method: method,
+					Path:   path,
+					Target: target,
+					Public: access.publicRoutes[routeKey(method, path)] || (target != "" && grpcServer.public[target]),
+				})
+			}
+			return nil
+		})
+	}
+	if err := walk("http", &httpServer.routeAccess); err != nil {
+		return nil, err
+	}
+	if adminServer != nil {
+		if err := walk("admin", adminServer.access); err != nil {
+			return nil, err
+		}
+	}
+
+	var grpcRoutes []Route
+	for name, info := range grpcServer.server.GetServiceInfo() {
+		for _, method := range info.Methods {
+			fullMethod := "/" + name + "/" + method.Name
+			grpcRoutes = append(grpcRoutes, Route{
+				Server: "grpc",
+				Path:   fullMethod,
+				Public: grpcServer.public[fullMethod],
+			})
+		}
+	}
+	sort.Slice(grpcRoutes, func(i, j int) bool {
+		return grpcRoutes[i].Path < grpcRoutes[j].Path
+	})
+
+	return append(routes, grpcRoutes...), nil
+}
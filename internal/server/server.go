@@ -2,23 +2,44 @@ package server
 
 import (
 	"context"
-	"os"
-	"os/signal"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
 	"runtime/debug"
-	"syscall"
+	"sync/atomic"
 	"time"
 
+	"github.com/Gambitier/voidkitgo/internal/auth"
+	"github.com/Gambitier/voidkitgo/internal/authz"
+	"github.com/Gambitier/voidkitgo/internal/cache"
+	"github.com/Gambitier/voidkitgo/internal/certs"
 	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/database"
+	"github.com/Gambitier/voidkitgo/internal/database/migrations"
+	"github.com/Gambitier/voidkitgo/internal/health"
+	"github.com/Gambitier/voidkitgo/internal/metrics"
+	"github.com/Gambitier/voidkitgo/internal/ratelimit"
+	"github.com/Gambitier/voidkitgo/internal/server/gateway"
+	httpHandlers "github.com/Gambitier/voidkitgo/internal/server/handlers/http"
+	"github.com/Gambitier/voidkitgo/internal/server/handlers/http/admin"
 	"github.com/Gambitier/voidkitgo/internal/services"
+	"github.com/Gambitier/voidkitgo/internal/tracing"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
-// Server represents the main server that coordinates HTTP and gRPC servers
+// Server represents the main server that coordinates the lifecycle of its components
 type Server struct {
 	config     *config.Config
 	logger     *logrus.Logger
 	httpServer *httpServer
 	grpcServer GrpcServer
+	lifecycle  lifecycle
+	ready      atomic.Bool
+	watcher    *config.Watcher
+	overrides  []any
 }
 
 // NewServer creates a new server instance
@@ -36,84 +57,384 @@ func (s *Server) recoverPanic() {
 	}
 }
 
-// Start starts both HTTP and gRPC servers
+// Register adds a component to the server. Components are started in
+// registration order before the gRPC and HTTP servers, and stopped in reverse.
+func (s *Server) Register(component Component, opts ...ComponentOption) {
+	s.lifecycle.add(component, opts...)
+}
+
+// WatchConfig registers a config watcher, whose reloads update the log level,
+// rate limits and feature flags while the server runs
+func (s *Server) WatchConfig(watcher *config.Watcher) {
+	s.watcher = watcher
+}
+
+// Override replaces the providers of the services returned by constructors,
+// so tests can run the server with fakes. It must be called before Start.
+func (s *Server) Override(constructors ...any) {
+	s.overrides = append(s.overrides, constructors...)
+}
+
+// Ready reports whether the server is started and not shutting down
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// Start starts all registered components along with the HTTP and gRPC servers,
+// and blocks until ctx is done or a component exits, then shuts down gracefully
 func (s *Server) Start(ctx context.Context) error {
 	// Add panic recovery for the main thread
 	defer s.recoverPanic()
 
-	// Create services
-	services := services.NewServices()
+	// Components may hold resources such as pools from their creation, they
+	// are released when the server fails to be set up
+	if err := s.setup(ctx); err != nil {
+		return errors.Join(err, s.lifecycle.release(context.WithoutCancel(ctx)))
+	}
+
+	if err := s.lifecycle.start(ctx); err != nil {
+		return err
+	}
+	s.ready.Store(true)
 
-	// Initialize servers
-	s.httpServer = NewHTTPServer(HttpServerParams{
-		services:  services,
-		logger:    s.logger,
-		serverEnv: s.config.Server.Env,
+	// Block until the caller cancels ctx or a component exits
+	if err := s.lifecycle.wait(ctx); err != nil {
+		s.logger.Errorf("Server error: %v", err)
+	} else {
+		s.logger.Info("Shutdown requested")
+		s.drain()
+	}
+
+	// Graceful shutdown
+	return s.Shutdown(ctx)
+}
+
+// setup creates the servers and the components they use, and registers them
+// in start order
+func (s *Server) setup(ctx context.Context) error {
+	// Create the tracer provider, registered first so spans are flushed last
+	var tracingProvider *tracing.Provider
+	if s.config.Tracing.Enabled {
+		provider, err := tracing.New(ctx, &s.config.Tracing, semconv.DeploymentEnvironment(string(s.config.Server.Env)))
+		if err != nil {
+			return fmt.Errorf("failed to create tracer provider: %w", err)
+		}
+		tracingProvider = provider
+		s.Register(tracingProvider)
+	}
+
+	// Create metrics, shared by both servers and the services
+	var serverMetrics *metrics.Metrics
+	var metricsHandler http.Handler
+	if s.config.Server.Metrics.Enabled {
+		serverMetrics = metrics.New()
+		metricsHandler = serverMetrics.Handler()
+	}
+
+	// Create the Redis client shared by every service, closed on shutdown
+	var redisClient redis.UniversalClient
+	if s.config.Cache.Type == "redis" {
+		redisClient = cache.NewRedisClient(&s.config.Cache)
+		s.Register(cache.NewRedisClientComponent(redisClient))
+	}
+
+	// Create the database pool shared by every service, it must be reachable
+	// before the servers start and is closed after they stop
+	var db *database.DB
+	if s.config.Database.Enabled {
+		pool, err := database.New(&s.config.Database, s.logger)
+		if err != nil {
+			return err
+		}
+		db = pool
+		s.Register(db)
+
+		// Pending migrations are applied before anything uses the schema
+		if cfg := &s.config.Database.Migrations; cfg.OnStart {
+			if !s.config.Server.Env.IsDevelopment() {
+				s.logger.Warn("Migrations on start are meant for development, run the migrate subcommand before deploying instead")
+			}
+			migrator, err := database.NewMigrator(db, migrations.FS, cfg.Table, s.logger)
+			if err != nil {
+				return err
+			}
+			s.Register(database.NewMigrateOnStart(migrator, cfg.Timeout), WithStartTimeout(cfg.Timeout))
+		}
+	}
+
+	// Create services, their lifecycle hooks run once the shared clients are ready
+	services, err := services.NewServices(services.ServicesParams{
+		Cache:   &s.config.Cache,
+		Redis:   redisClient,
+		Metrics: serverMetrics,
+		DB:      db,
+
+		Features:  s.config.Features,
+		Overrides: s.overrides,
 	})
-	s.grpcServer = NewGrpcServer(GrpcServerParams{
-		Services:  services,
-		Logger:    s.logger,
-		ServerEnv: s.config.Server.Env,
+	if err != nil {
+		return err
+	}
+	s.Register(services.Container)
+
+	// Report not ready until every component is started and once draining begins
+	services.Health.RegisterReadiness("server", health.CheckerFunc(func(ctx context.Context) error {
+		if !s.Ready() {
+			return errors.New("server is not ready")
+		}
+		return nil
+	}))
+
+	// Create the authenticator, shared by both servers
+	var authenticator *auth.Authenticator
+	if s.config.Auth.Enabled {
+		a, err := auth.New(&s.config.Auth, s.logger)
+		if err != nil {
+			return fmt.Errorf("failed to create authenticator: %w", err)
+		}
+		authenticator = a
+	}
+
+	// Create the authorization engine, policies apply to authenticated callers
+	var authzEngine *authz.Engine
+	if s.config.Authz.Enabled {
+		if authenticator == nil {
+			return errors.New("authz requires auth to be enabled")
+		}
+		engine, err := authz.New(&s.config.Authz, s.logger)
+		if err != nil {
+			return fmt.Errorf("failed to create authorization engine: %w", err)
+		}
+		authzEngine = engine
+	}
+
+	// Create the rate and concurrency limits, shared by both servers
+	var rateLimits *RateLimits
+	if s.config.RateLimit.Enabled {
+		limits, err := s.newRateLimits(redisClient)
+		if err != nil {
+			return err
+		}
+		rateLimits = limits
+	}
+
+	// Apply reloaded config to the components it configures
+	if s.watcher != nil {
+		s.watcher.Subscribe(s.configSubscriber(services, rateLimits))
+		s.Register(s.watcher)
+	}
+
+	// The profile of the environment enables debugging aids
+	profile := s.config.Profile()
+
+	httpParams := HttpServerParams{
+		services: services,
+		logger:   s.logger,
+		config:   &s.config.Server.HTTP,
+		profile:  profile,
+		metrics:  serverMetrics,
+		tracing:  tracingProvider,
+		auth:     authenticator,
+		authz:    authzEngine,
+
+		rateLimits: rateLimits,
+	}
+	grpcParams := GrpcServerParams{
+		Services: services,
+		Logger:   s.logger,
+		Config:   &s.config.Server.GRPC,
+		Profile:  profile,
+		Metrics:  serverMetrics,
+		Tracing:  tracingProvider,
+		Auth:     authenticator,
+		Authz:    authzEngine,
+
+		RateLimits: rateLimits,
+	}
+
+	// Admin endpoints get their own listener when an admin port is configured
+	adminParams := admin.AdminHandlerParams{
+		Logger:      s.logger,
+		Metrics:     metricsHandler,
+		MetricsPath: s.config.Server.Metrics.Path,
+		Pprof:       profile.Pprof,
+	}
+	if profile.DebugEndpoints {
+		adminParams.Config = func() map[string]any {
+			if s.watcher != nil {
+				return s.watcher.Config().Redacted()
+			}
+			return s.config.Redacted()
+		}
+	}
+	if unprotectedAdmin(s.config) {
+		s.logger.Warn("Auth is disabled and the admin endpoints accept remote connections, serving only the metrics and the log level")
+		restrictAdmin(&adminParams)
+	}
+	adminHandlers := httpHandlers.NewAdminHttpHandlers(adminParams)
+	if adminConfig := &s.config.Server.Admin; adminConfig.Port > 0 {
+		s.Register(newAdminServer(adminServerParams{
+			config:        adminConfig,
+			http:          &s.config.Server.HTTP,
+			verboseErrors: profile.VerboseErrors,
+			handlers:      adminHandlers,
+			logger:        s.logger,
+			auth:          authenticator,
+			authz:         authzEngine,
+		}))
+	} else {
+		httpParams.admin = adminHandlers
+	}
+
+	// Certificates are watched for changes, the reloaders start before the servers
+	if cfg := &s.config.Server.HTTP.TLS; cfg.Enabled {
+		tlsConfig, err := s.newTLSConfig("http", cfg, "h2", "http/1.1")
+		if err != nil {
+			return err
+		}
+		httpParams.tls = tlsConfig
+	}
+	if cfg := &s.config.Server.GRPC.TLS; cfg.Enabled {
+		tlsConfig, err := s.newTLSConfig("grpc", cfg)
+		if err != nil {
+			return err
+		}
+		grpcParams.TLS = tlsConfig
+	}
+
+	// In single port mode both servers accept connections from a shared multiplexer
+	if s.config.Server.Listen.Mode.IsSingle() {
+		// The multiplexer sniffs plaintext protocols, it cannot see through TLS
+		if httpParams.tls != nil || grpcParams.TLS != nil {
+			return errors.New("tls is not supported in single listen mode")
+		}
+		mux := newMuxServer(s.config.Server.Listen.Port, s.logger)
+		s.Register(mux)
+		httpParams.listen = mux.httpListener()
+		httpParams.h2c = true
+		grpcParams.Listen = mux.grpcListener()
+	}
+
+	// Initialize servers
+	s.grpcServer = NewGrpcServer(grpcParams)
+
+	// Expose annotated gRPC methods as HTTP/JSON routes
+	gw := gateway.NewGateway(gateway.GatewayParams{
+		Logger:       s.logger,
+		Interceptor:  s.grpcServer.UnaryInterceptor(),
+		MaxBodyBytes: s.config.Server.HTTP.MaxBodyBytes,
 	})
+	s.grpcServer.RegisterServices(gw)
+	httpParams.gateway = gw
+
+	s.httpServer = NewHTTPServer(httpParams)
+
+	s.Register(s.grpcServer)
+	s.Register(s.httpServer)
+	return nil
+}
+
+// newTLSConfig loads the certificates of a listener and registers a reloader
+// keeping them up to date
+func (s *Server) newTLSConfig(name string, cfg *config.TLSConfig, nextProtos ...string) (*tls.Config, error) {
+	reloader, err := certs.NewReloader(name, cfg, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := reloader.ServerTLSConfig(nextProtos...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s tls configuration: %w", name, err)
+	}
+	s.Register(reloader)
+	return tlsConfig, nil
+}
 
-	// Start servers in goroutines
-	errChan := make(chan error, 2)
+// newRateLimits creates the limits of the configured backend, the redis
+// backend shares the Redis client of the cache
+func (s *Server) newRateLimits(redisClient redis.UniversalClient) (*RateLimits, error) {
+	cfg := &s.config.RateLimit
+	rules, err := ratelimit.NewRules(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	// Start HTTP server with panic recovery
-	go func() {
-		defer s.recoverPanic()
-		s.logger.Infof("Starting HTTP server on port %d", s.config.Server.HTTP.Port)
-		if err := s.httpServer.Start(&s.config.Server.HTTP); err != nil {
-			s.logger.Errorf("HTTP server error: %v", err)
-			errChan <- err
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Backend == "redis" {
+		if redisClient == nil {
+			return nil, errors.New("rate_limit.backend redis requires cache.type redis")
 		}
-	}()
-
-	// Start gRPC server with panic recovery
-	go func() {
-		defer s.recoverPanic()
-		s.logger.Infof("Starting gRPC server on port %d", s.config.Server.GRPC.Port)
-		if err := s.grpcServer.Start(s.config.Server.GRPC.Port); err != nil {
-			s.logger.Errorf("gRPC server error: %v", err)
-			errChan <- err
+		store = ratelimit.NewRedisStore(redisClient, cfg.KeyPrefix)
+	}
+
+	limits := &RateLimits{
+		config:  cfg,
+		limiter: ratelimit.NewLimiter(rules, store, s.logger),
+	}
+	if cfg.MaxInFlight > 0 {
+		limits.inFlight = ratelimit.NewConcurrencyLimiter(cfg.MaxInFlight)
+	}
+	return limits, nil
+}
+
+// configSubscriber applies the events of config reloads
+func (s *Server) configSubscriber(services *services.Services, rateLimits *RateLimits) func(config.Event) {
+	return func(event config.Event) {
+		switch event := event.(type) {
+		case config.LogLevelChanged:
+			level, err := logrus.ParseLevel(event.New)
+			if err != nil {
+				s.logger.Errorf("Failed to apply log level: %v", err)
+				return
+			}
+			s.logger.SetLevel(level)
+		case config.RateLimitChanged:
+			// Enabling rate limiting requires a restart, only rates change
+			if rateLimits == nil {
+				return
+			}
+			if err := rateLimits.update(&event.New); err != nil {
+				s.logger.Errorf("Failed to apply rate limits: %v", err)
+			}
+		case config.FeaturesChanged:
+			services.Features.Set(event.New)
 		}
-	}()
+	}
+}
 
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// drain reports the server as not ready and waits for the configured pre-stop
+// delay so load balancers stop routing traffic before listeners close
+func (s *Server) drain() {
+	s.ready.Store(false)
 
-	// Block until a signal is received or a server error occurs
-	select {
-	case err := <-errChan:
-		s.logger.Errorf("Server error: %v", err)
-	case sig := <-sigChan:
-		s.logger.Infof("Received signal: %v", sig)
+	delay := s.config.Server.Shutdown.PreStopDelay
+	if delay <= 0 {
+		return
 	}
 
-	// Graceful shutdown
-	return s.Shutdown(ctx)
+	s.logger.Infof("Reporting not ready for %s before stopping listeners", delay)
+	time.Sleep(delay)
 }
 
-// Shutdown gracefully shuts down both servers
+// Shutdown gracefully stops all components in reverse start order within the
+// configured drain timeout. The deadline is derived from ctx without its
+// cancellation, so an already cancelled ctx still allows a graceful drain.
+// It returns the aggregated errors of every component that failed to stop.
 func (s *Server) Shutdown(ctx context.Context) error {
 	defer s.recoverPanic()
 
+	s.ready.Store(false)
 	s.logger.Info("Shutting down servers...")
-	// Create a timeout context for shutdown
-	shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	// Shutdown HTTP server
-	if s.httpServer != nil {
-		if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
-			s.logger.Errorf("HTTP server shutdown error: %v", err)
-		}
+
+	shutdownCtx := context.WithoutCancel(ctx)
+	if timeout := s.config.Server.Shutdown.DrainTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, timeout)
+		defer cancel()
 	}
 
-	// Shutdown gRPC server
-	if s.grpcServer != nil {
-		s.grpcServer.Shutdown()
+	if err := s.lifecycle.stop(shutdownCtx); err != nil {
+		s.logger.Errorf("Server shutdown error: %v", err)
+		return err
 	}
 
 	s.logger.Info("Server shutdown complete")
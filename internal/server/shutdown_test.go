@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// slowComponent takes until its context is done to stop
+type slowComponent struct {
+	*testComponent
+}
+
+func (c *slowComponent) Stop(ctx context.Context) error {
+	c.testComponent.Stop(ctx)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newShutdownServer(shutdown config.ShutdownConfig) *Server {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{}
+	cfg.Server.Shutdown = shutdown
+	return NewServer(cfg, logger)
+}
+
+func TestShutdownDrainTimeout(t *testing.T) {
+	s := newShutdownServer(config.ShutdownConfig{DrainTimeout: 50 * time.Millisecond})
+	e := &events{}
+	s.Register(&slowComponent{newTestComponent("slow", e)})
+	if err := s.lifecycle.start(context.Background()); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+
+	// A cancelled context still gets the whole drain timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err := s.Shutdown(ctx)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want the drain timeout exceeded", err)
+	}
+	if elapsed < 50*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Shutdown() took %s, want the 50ms drain timeout", elapsed)
+	}
+}
+
+func TestDrainPreStopDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{name: "no delay"},
+		{name: "delay", delay: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newShutdownServer(config.ShutdownConfig{PreStopDelay: tt.delay})
+			s.ready.Store(true)
+
+			done := make(chan struct{})
+			start := time.Now()
+			go func() {
+				s.drain()
+				close(done)
+			}()
+
+			// The server reports not ready for the whole delay
+			for deadline := time.Now().Add(time.Second); s.Ready(); time.Sleep(time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatal("server still ready while draining")
+				}
+			}
+			<-done
+			if elapsed := time.Since(start); elapsed < tt.delay {
+				t.Errorf("drain() took %s, want at least %s", elapsed, tt.delay)
+			}
+		})
+	}
+}
@@ -1,11 +1,124 @@
 package services
 
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Gambitier/voidkitgo/internal/cache"
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/Gambitier/voidkitgo/internal/database"
+	"github.com/Gambitier/voidkitgo/internal/di"
+	"github.com/Gambitier/voidkitgo/internal/features"
+	"github.com/Gambitier/voidkitgo/internal/health"
+	"github.com/Gambitier/voidkitgo/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// Services holds the services used by the handlers. Every field is resolved
+// from the container by its type, so adding a service takes a field and the
+// registration of its constructor in provide.
 type Services struct {
+	// Container creates the services, it is started and stopped by the server
+	// to run the lifecycle hooks of the services
+	Container *di.Container
+	// Health collects the named checks contributed by services
+	Health *health.Registry
+	// Cache is the cache shared by every service
+	Cache cache.Cache
+	// Redis is the shared Redis client, nil unless the cache type is redis
+	Redis redis.UniversalClient
+	// DB is the shared PostgreSQL pool, nil unless the database is enabled
+	DB *database.DB
+	// Repository is the base embedded by repositories, it runs queries in the
+	// transaction of the context when there is one, and fails them with
+	// database.ErrDisabled when the database is disabled
+	Repository database.Repository
+	// Features holds the feature flags, updated when the config is reloaded
+	Features *features.Flags
+
 	// Add services here
 }
 
-func NewServices() *Services {
-	return &Services{
-		// set services here
+type ServicesParams struct {
+	Cache *config.CacheConfig
+	// Redis is the shared client, required when the cache type is redis
+	Redis redis.UniversalClient
+	// Metrics instruments the cache, nil disables it
+	Metrics *metrics.Metrics
+	// DB is the shared PostgreSQL pool, nil when the database is disabled
+	DB *database.DB
+	// Features are the initial feature flags
+	Features map[string]bool
+	// Overrides are constructors replacing the providers of the types they
+	// return, such as fakes in tests
+	Overrides []any
+}
+
+// NewServices registers the providers of the services and resolves them
+func NewServices(params ServicesParams) (*Services, error) {
+	container := di.New()
+	if err := provide(container, params); err != nil {
+		return nil, fmt.Errorf("failed to register services: %w", err)
+	}
+	for _, override := range params.Overrides {
+		if err := container.Override(override); err != nil {
+			return nil, fmt.Errorf("failed to override service: %w", err)
+		}
+	}
+	if err := container.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid service dependencies: %w", err)
+	}
+
+	services := &Services{}
+	if err := container.Populate(services); err != nil {
+		return nil, fmt.Errorf("failed to create services: %w", err)
+	}
+	return services, nil
+}
+
+// provide registers the constructors of the services, the clients shared
+// with the server are supplied as they are
+func provide(container *di.Container, params ServicesParams) error {
+	return errors.Join(
+		di.Supply(container, params.Cache),
+		di.Supply(container, params.Redis),
+		di.Supply(container, params.Metrics),
+		di.Supply(container, params.DB),
+		container.Provide(health.NewRegistry),
+		container.Provide(func() *features.Flags { return features.New(params.Features) }),
+		container.Provide(newCache),
+		container.Provide(newRepository),
+		// register services here, adding their checks to the health registry
+	)
+}
+
+// newCache creates the Redis cache when a client is given, an in-memory cache otherwise
+func newCache(cfg *config.CacheConfig, client redis.UniversalClient, m *metrics.Metrics, registry *health.Registry) cache.Cache {
+	options := cache.Options{
+		KeyPrefix:  cfg.KeyPrefix,
+		DefaultTTL: cfg.DefaultTTL,
+		MaxEntries: cfg.MaxEntries,
+	}
+	if m != nil {
+		options.Observer = m
+	}
+
+	var c cache.Cache
+	if client != nil {
+		c = cache.NewRedis(client, options)
+	} else {
+		c = cache.NewMemory(options)
+	}
+	registry.RegisterReadiness("cache", health.CheckerFunc(c.Ping))
+	return c
+}
+
+// newRepository creates the base repository, whose queries fail with
+// database.ErrDisabled when the database is disabled
+func newRepository(db *database.DB, registry *health.Registry) database.Repository {
+	if db == nil {
+		return database.Repository{}
 	}
+	registry.RegisterReadiness("database", health.CheckerFunc(db.Ping))
+	return database.NewRepository(db)
 }
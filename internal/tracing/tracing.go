@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported span exporters
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// instrumentationName names the tracer creating the server spans
+const instrumentationName = "github.com/Gambitier/voidkitgo/internal/server"
+
+// Provider owns the tracer provider and the W3C propagator. It is managed as
+// a server component so buffered spans are flushed on shutdown.
+type Provider struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	closer     io.Closer
+	done       chan struct{}
+}
+
+// New creates a tracer provider from the tracing configuration and installs
+// it, along with the trace context and baggage propagators, as the global one
+func New(ctx context.Context, cfg *config.TracingConfig, attrs ...attribute.KeyValue) (*Provider, error) {
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return &Provider{
+		provider:   provider,
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagator,
+		closer:     closer,
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// newExporter creates the configured span exporter and the file it writes
+// to, if any
+func newExporter(ctx context.Context, cfg *config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case "", ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLP.Endpoint)}
+		if cfg.OTLP.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if cfg.OTLP.Timeout > 0 {
+			opts = append(opts, otlptracegrpc.WithTimeout(cfg.OTLP.Timeout))
+		}
+		if len(cfg.OTLP.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.OTLP.Headers))
+		}
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil, nil
+
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+
+	case ExporterFile:
+		if cfg.File == "" {
+			return nil, nil, fmt.Errorf("tracing.file is required when tracing.exporter is %s", ExporterFile)
+		}
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create trace file directory: %w", err)
+		}
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exporter, file, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported tracing exporter %q", cfg.Exporter)
+}
+
+// Tracer returns the tracer used for server spans
+func (p *Provider) Tracer() trace.Tracer {
+	return p.tracer
+}
+
+// Propagator returns the propagator extracting incoming trace context
+func (p *Provider) Propagator() propagation.TextMapPropagator {
+	return p.propagator
+}
+
+// Name returns the component name
+func (p *Provider) Name() string {
+	return "tracing"
+}
+
+// Start blocks until the provider is stopped, spans are exported in the background
+func (p *Provider) Start(ctx context.Context) error {
+	<-p.done
+	return nil
+}
+
+// Stop flushes buffered spans and shuts down the exporter
+func (p *Provider) Stop(ctx context.Context) error {
+	defer close(p.done)
+
+	err := p.provider.Shutdown(ctx)
+	if p.closer != nil {
+		if closeErr := p.closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Ready always reports true, the provider is usable as soon as it is created
+func (p *Provider) Ready() bool {
+	return true
+}
+
+// LogFields returns the trace and span IDs of the span in ctx as log fields,
+// or nil when ctx carries no valid span
+func LogFields(ctx context.Context) logrus.Fields {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return logrus.Fields{
+		"trace_id": spanContext.TraceID().String(),
+		"span_id":  spanContext.SpanID().String(),
+	}
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Gambitier/voidkitgo/internal/config"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.json")
+	p, err := New(context.Background(), &config.TracingConfig{
+		ServiceName: "test-service",
+		Exporter:    ExporterFile,
+		SampleRatio: 1,
+		File:        path,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, span := p.Tracer().Start(context.Background(), "test-span")
+	traceID := span.SpanContext().TraceID().String()
+	span.End()
+
+	// Buffered spans are written when the provider stops
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"test-span", traceID, "test-service"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("trace file does not contain %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.TracingConfig
+		want string
+	}{
+		{
+			name: "file without path",
+			cfg:  config.TracingConfig{Exporter: ExporterFile},
+			want: "tracing.file is required",
+		},
+		{
+			name: "unsupported exporter",
+			cfg:  config.TracingConfig{Exporter: "zipkin"},
+			want: `unsupported tracing exporter "zipkin"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(context.Background(), &tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	p, err := New(context.Background(), &config.TracingConfig{
+		ServiceName: "test-service",
+		Exporter:    ExporterFile,
+		SampleRatio: 1,
+		File:        filepath.Join(t.TempDir(), "spans.json"),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer p.Stop(context.Background())
+
+	ctx, span := p.Tracer().Start(context.Background(), "client")
+	defer span.End()
+
+	md := metadata.MD{}
+	p.Propagator().Inject(ctx, MetadataCarrier(md))
+	if got := md.Get("traceparent"); len(got) != 1 {
+		t.Fatalf("traceparent = %v, want one value", got)
+	}
+	keys := MetadataCarrier(md).Keys()
+	sort.Strings(keys)
+	if want := []string{"traceparent"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys() = %v, want %v", keys, want)
+	}
+
+	// The server side continues the trace of the caller
+	extracted := trace.SpanContextFromContext(p.Propagator().Extract(context.Background(), MetadataCarrier(md)))
+	if extracted.TraceID() != span.SpanContext().TraceID() || !extracted.IsRemote() {
+		t.Errorf("extracted span context = %v, want the remote parent %v", extracted, span.SpanContext())
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	if got := LogFields(context.Background()); got != nil {
+		t.Errorf("LogFields() without a span = %v, want nil", got)
+	}
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	want := logrus.Fields{
+		"trace_id": "01000000000000000000000000000000",
+		"span_id":  "0200000000000000",
+	}
+	if got := LogFields(trace.ContextWithSpanContext(context.Background(), spanContext)); !reflect.DeepEqual(got, want) {
+		t.Errorf("LogFields() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: proto/common/options.proto
+
+package common
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Policy declares who may call a method, every condition that is set must hold
+type Policy struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// roles grants access to callers holding any of the roles
+	Roles []string `protobuf:"bytes,1,rep,name=roles,proto3" json:"roles,omitempty"`
+	// scopes grants access to tokens issued with all of the scopes
+	Scopes []string `protobuf:"bytes,2,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	// expr is an expression such as "has_role('admin') || claims.tenant == 'acme'"
+	Expr          string `protobuf:"bytes,3,opt,name=expr,proto3" json:"expr,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Policy) Reset() {
+	*x = Policy{}
+	mi := &file_proto_common_options_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Policy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Policy) ProtoMessage() {}
+
+func (x *Policy) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_common_options_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Policy.ProtoReflect.Descriptor instead.
+func (*Policy) Descriptor() ([]byte, []int) {
+	return file_proto_common_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Policy) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *Policy) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *Policy) GetExpr() string {
+	if x != nil {
+		return x.Expr
+	}
+	return ""
+}
+
+var file_proto_common_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*Policy)(nil),
+		Field:         50001,
+		Name:          "common.v1.policy",
+		Tag:           "bytes,50001,opt,name=policy",
+		Filename:      "proto/common/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// policy is enforced on every call of the method, unless overridden in config
+	//
+	// optional common.v1.Policy policy = 50001;
+	E_Policy = &file_proto_common_options_proto_extTypes[0]
+)
+
+var File_proto_common_options_proto protoreflect.FileDescriptor
+
+const file_proto_common_options_proto_rawDesc = "" +
+	"\n" +
+	"\x1aproto/common/options.proto\x12\tcommon.v1\x1a google/protobuf/descriptor.proto\"J\n" +
+	"\x06Policy\x12\x14\n" +
+	"\x05roles\x18\x01 \x03(\tR\x05roles\x12\x16\n" +
+	"\x06scopes\x18\x02 \x03(\tR\x06scopes\x12\x12\n" +
+	"\x04expr\x18\x03 \x01(\tR\x04expr:K\n" +
+	"\x06policy\x12\x1e.google.protobuf.MethodOptions\x18ц\x03 \x01(\v2\x11.common.v1.PolicyR\x06policyB-Z+github.com/Gambitier/voidkitgo/proto/commonb\x06proto3"
+
+var (
+	file_proto_common_options_proto_rawDescOnce sync.Once
+	file_proto_common_options_proto_rawDescData []byte
+)
+
+func file_proto_common_options_proto_rawDescGZIP() []byte {
+	file_proto_common_options_proto_rawDescOnce.Do(func() {
+		file_proto_common_options_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_common_options_proto_rawDesc), len(file_proto_common_options_proto_rawDesc)))
+	})
+	return file_proto_common_options_proto_rawDescData
+}
+
+var file_proto_common_options_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proto_common_options_proto_goTypes = []any{
+	(*Policy)(nil),                     // 0: common.v1.Policy
+	(*descriptorpb.MethodOptions)(nil), // 1: google.protobuf.MethodOptions
+}
+var file_proto_common_options_proto_depIdxs = []int32{
+	1, // 0: common.v1.policy:extendee -> google.protobuf.MethodOptions
+	0, // 1: common.v1.policy:type_name -> common.v1.Policy
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	1, // [1:2] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_common_options_proto_init() }
+func file_proto_common_options_proto_init() {
+	if File_proto_common_options_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_common_options_proto_rawDesc), len(file_proto_common_options_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_common_options_proto_goTypes,
+		DependencyIndexes: file_proto_common_options_proto_depIdxs,
+		MessageInfos:      file_proto_common_options_proto_msgTypes,
+		ExtensionInfos:    file_proto_common_options_proto_extTypes,
+	}.Build()
+	File_proto_common_options_proto = out.File
+	file_proto_common_options_proto_goTypes = nil
+	file_proto_common_options_proto_depIdxs = nil
+}
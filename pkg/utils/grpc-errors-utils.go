@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/Gambitier/voidkitgo/proto/common"
+	"github.com/Gambitier/voidkitgo/pkg/proto/common"
 )
 
 // ResultWithError represents a proto-generated response type that has Success and Error fields